@@ -0,0 +1,213 @@
+// H25.4/30 (鈴)
+
+// このファイルは整数のビット演算と整数論的な演算を実装する。
+// 負の整数は無限に続く 2 の補数表現として扱う。
+// 引数がともに int32 ならば int64 で計算し，そうでなければ
+// *Int で計算する。
+
+package arith
+
+import (
+	. "fmt"
+	"math"
+	. "math/big"
+	"math/bits"
+)
+
+// ビットごとの論理積: LogAnd(12, 10) => 8
+func LogAnd(a, b Number) Number {
+	switch x := a.(type) {
+	case int32:
+		switch y := b.(type) {
+		case int32:
+			return x & y
+		}
+	}
+	return regulateInt(new(Int).And(toInt(a), toInt(b)))
+}
+
+// ビットごとの論理和: LogIor(12, 10) => 14
+func LogIor(a, b Number) Number {
+	switch x := a.(type) {
+	case int32:
+		switch y := b.(type) {
+		case int32:
+			return x | y
+		}
+	}
+	return regulateInt(new(Int).Or(toInt(a), toInt(b)))
+}
+
+// ビットごとの排他的論理和: LogXor(12, 10) => 6
+func LogXor(a, b Number) Number {
+	switch x := a.(type) {
+	case int32:
+		switch y := b.(type) {
+		case int32:
+			return x ^ y
+		}
+	}
+	return regulateInt(new(Int).Xor(toInt(a), toInt(b)))
+}
+
+// ビットごとの否定 (-a-1): LogNot(0) => -1
+func LogNot(a Number) Number {
+	switch x := a.(type) {
+	case int32:
+		return ^x
+	}
+	return regulateInt(new(Int).Not(toInt(a)))
+}
+
+// 算術シフト: Ash(1, 10) => 1024, Ash(-5, -1) => -3
+// count が正ならば左へ，負ならば右へ (負の無限大の方向へ丸めて) シフトする。
+func Ash(a, count Number) Number {
+	n := toInt(count)
+	if !n.IsInt64() || n.Int64() > math.MaxInt32 ||
+		n.Int64() < math.MinInt32 {
+		panic(Errorf("shift count too large: %v", count))
+	}
+	c := n.Int64()
+	switch x := a.(type) {
+	case int32:
+		if c <= 0 {
+			if c < -31 {
+				c = -31
+			}
+			return x >> uint(-c)
+		} else if c <= 32 {
+			return regulateInt64(int64(x) << uint(c))
+		}
+	}
+	if c >= 0 {
+		return regulateInt(new(Int).Lsh(toInt(a), uint(c)))
+	}
+	return regulateInt(new(Int).Rsh(toInt(a), uint(-c)))
+}
+
+// 符号ビットを除いた 2 の補数表現のビット長: IntegerLength(255) => 8,
+// IntegerLength(-256) => 8
+func IntegerLength(a Number) int {
+	switch x := a.(type) {
+	case int32:
+		if x < 0 {
+			x = ^x
+		}
+		return bits.Len32(uint32(x))
+	}
+	x := toInt(a)
+	if x.Sign() < 0 {
+		return new(Int).Not(x).BitLen()
+	}
+	return x.BitLen()
+}
+
+// 1 であるビットの数 (負数では 0 であるビットの数): BitCount(7) => 3,
+// BitCount(-8) => 3
+func BitCount(a Number) int {
+	switch x := a.(type) {
+	case int32:
+		if x < 0 {
+			x = ^x
+		}
+		return bits.OnesCount32(uint32(x))
+	}
+	x := toInt(a)
+	if x.Sign() < 0 {
+		x = new(Int).Not(x)
+	}
+	count := 0
+	for _, w := range x.Bits() {
+		count += bits.OnesCount(uint(w))
+	}
+	return count
+}
+
+// 非負整数の平方根以下の最大の整数: Isqrt(17) => 4
+func Isqrt(a Number) Number {
+	if Sign(a) < 0 {
+		panic(Errorf("negative argument: %v", a))
+	}
+	switch x := a.(type) {
+	case int32:
+		r := int64(math.Sqrt(float64(x)))
+		for r*r > int64(x) {
+			r--
+		}
+		for (r+1)*(r+1) <= int64(x) {
+			r++
+		}
+		return int32(r)
+	}
+	return regulateInt(new(Int).Sqrt(toInt(a)))
+}
+
+// 冪剰余 b^e mod m (0 <= 結果 < m): ExptMod(4, 13, 497) => 445
+// e が負ならば b の法 m での逆元の累乗を求める。逆元がなければ panic する。
+func ExptMod(b, e, m Number) Number {
+	if Sign(m) <= 0 {
+		panic(Errorf("modulus must be positive: %v", m))
+	}
+	switch x := b.(type) {
+	case int32:
+		switch y := e.(type) {
+		case int32:
+			switch z := m.(type) {
+			case int32:
+				if y >= 0 {
+					return exptModInt64(int64(x), int64(y), int64(z))
+				}
+			}
+		}
+	}
+	r := new(Int).Exp(toInt(b), toInt(e), toInt(m))
+	if r == nil {
+		panic(Errorf("no inverse of %v modulo %v", b, m))
+	}
+	return regulateInt(r)
+}
+
+// int64 による冪剰余. m は int32 の範囲の正数とし，e は非負とする。
+func exptModInt64(b, e, m int64) Number {
+	b %= m
+	if b < 0 {
+		b += m
+	}
+	r := int64(1) % m
+	for ; e > 0; e >>= 1 {
+		if e&1 != 0 {
+			r = r * b % m
+		}
+		b = b * b % m
+	}
+	return int32(r)
+}
+
+// 素数ならば true を返す。Miller-Rabin 法と Baillie-PSW 法による
+// 判定であり，2^64 未満では誤りがない。IsPrime(97) => true
+func IsPrime(a Number) bool {
+	x := toInt(a)
+	return x.Sign() > 0 && x.ProbablyPrime(20)
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
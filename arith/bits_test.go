@@ -0,0 +1,153 @@
+// H25.4/30 (鈴)
+
+package arith
+
+import (
+	. "fmt"
+	. "math/big"
+)
+
+var two100 = new(Int).Lsh(NewInt(1), 100)
+
+func ExampleLogAnd() {
+	r := LogAnd(12, 10)
+	Printf("%T %v\n", r, r)
+	r = LogAnd(-1, 10)
+	Printf("%T %v\n", r, r)
+	r = LogAnd(new(Int).Sub(two100, NewInt(1)), -256)
+	Printf("%T %v\n", r, r)
+	r = LogAnd(two100, 255)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 8
+	// int32 10
+	// *big.Int 1267650600228229401496703205120
+	// int32 0
+}
+
+func ExampleLogIor() {
+	r := LogIor(12, 10)
+	Printf("%T %v\n", r, r)
+	r = LogIor(two100, 1)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 14
+	// *big.Int 1267650600228229401496703205377
+}
+
+func ExampleLogXor() {
+	r := LogXor(12, 10)
+	Printf("%T %v\n", r, r)
+	r = LogXor(two100, two100)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 6
+	// int32 0
+}
+
+func ExampleLogNot() {
+	r := LogNot(0)
+	Printf("%T %v\n", r, r)
+	r = LogNot(int32(-2147483648))
+	Printf("%T %v\n", r, r)
+	r = LogNot(two100)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 -1
+	// int32 2147483647
+	// *big.Int -1267650600228229401496703205377
+}
+
+func ExampleAsh() {
+	r := Ash(1, 10)
+	Printf("%T %v\n", r, r)
+	r = Ash(-5, -1)
+	Printf("%T %v\n", r, r)
+	r = Ash(1, 100)
+	Printf("%T %v\n", r, r)
+	r = Ash(two100, -99)
+	Printf("%T %v\n", r, r)
+	r = Ash(-1, -100)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 1024
+	// int32 -3
+	// *big.Int 1267650600228229401496703205376
+	// int32 2
+	// int32 -1
+}
+
+func ExampleIntegerLength() {
+	Println(IntegerLength(0), IntegerLength(255), IntegerLength(256),
+		IntegerLength(-256), IntegerLength(-257), IntegerLength(two100))
+	// Output:
+	// 0 8 9 8 9 101
+}
+
+func ExampleBitCount() {
+	Println(BitCount(7), BitCount(-8), BitCount(two100),
+		BitCount(new(Int).Neg(two100)))
+	// Output:
+	// 3 3 1 100
+}
+
+func ExampleIsqrt() {
+	r := Isqrt(17)
+	Printf("%T %v\n", r, r)
+	r = Isqrt(2147483647)
+	Printf("%T %v\n", r, r)
+	r = Isqrt(two100)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 4
+	// int32 46340
+	// *big.Int 1125899906842624
+}
+
+func ExampleExptMod() {
+	r := ExptMod(4, 13, 497)
+	Printf("%T %v\n", r, r)
+	r = ExptMod(-4, 3, 7)
+	Printf("%T %v\n", r, r)
+	r = ExptMod(3, -1, 7)
+	Printf("%T %v\n", r, r)
+	r = ExptMod(2, 100, two100)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 445
+	// int32 6
+	// int32 5
+	// int32 0
+}
+
+func ExampleIsPrime() {
+	Println(IsPrime(1), IsPrime(2), IsPrime(97), IsPrime(561), IsPrime(-7))
+	m127 := new(Int).Sub(new(Int).Lsh(NewInt(1), 127),
+		NewInt(1))
+	Println(IsPrime(m127), IsPrime(two100))
+	// Output:
+	// false true true false false
+	// true false
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,143 @@
+// H25.4/28 (鈴)
+
+// このファイルは数の様々な書式での文字列表現を実装する。
+// String と異なり，用途に応じて桁数や基数を指定できる。
+
+package arith
+
+import (
+	. "fmt"
+	"math"
+	. "math/big"
+	"strconv"
+	"strings"
+)
+
+// 数の 10 進小数表記を得る。正確数は循環小数の循環節を括弧で囲んで
+// 正確に表す: FormatDecimal(NewRat(1, 6), 100) => "0.1(6)"
+// 小数部が maxDigits 桁を越えても循環節が見つからなければ，
+// そこで打ち切って "..." を付ける。float64 は String と同じ表記になる。
+func FormatDecimal(a Number, maxDigits int) string {
+	switch x := regulateArg(a).(type) {
+	case float64:
+		return String(x)
+	case *Rat:
+		return ratToDecimal(x, maxDigits)
+	}
+	return String(a)
+}
+
+// 有理数を循環節付きの 10 進小数表記にする。
+func ratToDecimal(x *Rat, maxDigits int) string {
+	var sb strings.Builder
+	if x.Sign() < 0 {
+		sb.WriteByte('-')
+	}
+	n := new(Int).Abs(x.Num())
+	d := x.Denom()
+	q, r := new(Int).QuoRem(n, d, new(Int))
+	sb.WriteString(q.String())
+	sb.WriteByte('.')
+	digits := make([]byte, 0, 16)
+	seen := make(map[string]int) // 剰余から，それが現れた桁の位置への表
+	ten := NewInt(10)
+	digit := new(Int)
+	for r.Sign() != 0 {
+		key := r.String()
+		if i, ok := seen[key]; ok { // 循環節が見つかった
+			sb.Write(digits[:i])
+			sb.WriteByte('(')
+			sb.Write(digits[i:])
+			sb.WriteByte(')')
+			return sb.String()
+		}
+		if len(digits) >= maxDigits {
+			sb.Write(digits)
+			sb.WriteString("...")
+			return sb.String()
+		}
+		seen[key] = len(digits)
+		r.Mul(r, ten)
+		digit.QuoRem(r, d, r)
+		digits = append(digits, byte('0'+digit.Int64()))
+	}
+	sb.Write(digits)
+	return sb.String()
+}
+
+// 数を小数点以下 prec 桁の固定小数点表記にする。
+// FormatFixed(NewRat(2, 3), 3) => "0.667"
+// 正確数では最後の桁を四捨五入 (ちょうど中間ならばゼロから遠い方へ) する。
+func FormatFixed(a Number, prec int) string {
+	switch x := regulateArg(a).(type) {
+	case float64:
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return String(x)
+		}
+		return strconv.FormatFloat(x, 'f', prec, 64)
+	case *Rat:
+		return x.FloatString(prec)
+	}
+	return toRat(a).FloatString(prec)
+}
+
+// 数を仮数部の小数点以下 prec 桁の指数表記にする。
+// FormatScientific(123456, 2) => "1.23e+05"
+func FormatScientific(a Number, prec int) string {
+	switch x := regulateArg(a).(type) {
+	case float64:
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return String(x)
+		}
+		return strconv.FormatFloat(x, 'e', prec, 64)
+	}
+	// 正確数は十分な精度の Float に変換して丸める。
+	x := toRat(a)
+	bits := uint(64 + 4*prec + x.Num().BitLen() + x.Denom().BitLen())
+	return new(Float).SetPrec(bits).SetRat(x).Text('e', prec)
+}
+
+// 数を基数 radix (2 から 36 まで) で表記する。
+// FormatRadix(255, 16) => "ff", FormatRadix(NewRat(-1, 2), 2) => "-1/10"
+// float64 は基数 10 のときだけ表記できる。
+func FormatRadix(a Number, radix int) string {
+	if radix < 2 || radix > 36 {
+		panic(Errorf("radix out of range: %d", radix))
+	}
+	switch x := regulateArg(a).(type) {
+	case int32:
+		return strconv.FormatInt(int64(x), radix)
+	case float64:
+		if radix != 10 {
+			panic(Errorf("float in radix %d: %s", radix, String(x)))
+		}
+		return String(x)
+	case *Int:
+		return x.Text(radix)
+	case *Rat:
+		return x.Num().Text(radix) + "/" + x.Denom().Text(radix)
+	}
+	panic(Errorf("unsupported type: %T", a))
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
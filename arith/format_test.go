@@ -0,0 +1,99 @@
+// H25.4/28 (鈴)
+
+package arith
+
+import (
+	. "fmt"
+	"math"
+	. "math/big"
+)
+
+func ExampleFormatDecimal() {
+	Println(FormatDecimal(7, 100))
+	Println(FormatDecimal(NewRat(1, 4), 100))
+	Println(FormatDecimal(NewRat(1, 3), 100))
+	Println(FormatDecimal(NewRat(-1, 6), 100))
+	Println(FormatDecimal(NewRat(22, 7), 100))
+	Println(FormatDecimal(NewRat(1, 97), 20))
+	Println(FormatDecimal(1.25, 100))
+	Println(FormatDecimal(DivideReal(Expt(10, 30), 3), 100))
+	// Output:
+	// 7
+	// 0.25
+	// 0.(3)
+	// -0.1(6)
+	// 3.(142857)
+	// 0.01030927835051546391...
+	// 1.25
+	// 333333333333333333333333333333.(3)
+}
+
+func ExampleFormatFixed() {
+	Println(FormatFixed(7, 2))
+	Println(FormatFixed(NewRat(2, 3), 3))
+	Println(FormatFixed(NewRat(-5, 2), 0))
+	Println(FormatFixed(3.14159, 2))
+	Println(FormatFixed(Expt(10, 20), 1))
+	Println(FormatFixed(math.Inf(-1), 2))
+	// Output:
+	// 7.00
+	// 0.667
+	// -3
+	// 3.14
+	// 100000000000000000000.0
+	// -inf.0
+}
+
+func ExampleFormatScientific() {
+	Println(FormatScientific(123456, 2))
+	Println(FormatScientific(NewRat(1, 3), 4))
+	Println(FormatScientific(NewRat(-2, 30000), 1))
+	Println(FormatScientific(6.02214076e23, 3))
+	Println(FormatScientific(Expt(3, 400), 5))
+	Println(FormatScientific(0, 2))
+	// Output:
+	// 1.23e+05
+	// 3.3333e-01
+	// -6.7e-05
+	// 6.022e+23
+	// 7.05508e+190
+	// 0.00e+00
+}
+
+func ExampleFormatRadix() {
+	Println(FormatRadix(255, 16))
+	Println(FormatRadix(-10, 2))
+	Println(FormatRadix(NewRat(-1, 2), 2))
+	Println(FormatRadix(Expt(2, 64), 16))
+	Println(FormatRadix(35, 36))
+	Println(FormatRadix(1.5, 10))
+	// Output:
+	// ff
+	// -1010
+	// -1/10
+	// 10000000000000000
+	// z
+	// 1.5
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,236 @@
+// H25.4/26 (鈴)
+
+// このファイルは平方根，指数関数，対数関数，三角関数などの超越関数を
+// 実装する。結果が正確数で表せる自明な場合は正確数を返し，
+// それ以外は float64 で計算する。
+
+package arith
+
+import (
+	. "fmt"
+	"math"
+	. "math/big"
+	"strconv"
+)
+
+// 円周率 π と自然対数の底 e
+var (
+	Pi Number = math.Pi
+	E  Number = math.E
+)
+
+// NaN ならば true を返す。
+func IsNaN(a Number) bool {
+	x, ok := a.(float64)
+	return ok && math.IsNaN(x)
+}
+
+// 無限大 (+Inf または -Inf) ならば true を返す。
+func IsInf(a Number) bool {
+	x, ok := a.(float64)
+	return ok && math.IsInf(x, 0)
+}
+
+// 正確数の値が b に等しいならば true を返す。
+func isExact(a Number, b int64) bool {
+	switch x := regulateArg(a).(type) {
+	case int32:
+		return int64(x) == b
+	case *Int:
+		return x.IsInt64() && x.Int64() == b
+	}
+	return false
+}
+
+// 非負の多倍長整数の平方根が整数ならばそれを返す。さもなくば nil を返す。
+func exactSqrt(a *Int) *Int {
+	r := new(Int).Sqrt(a)
+	if new(Int).Mul(r, r).Cmp(a) == 0 {
+		return r
+	}
+	return nil
+}
+
+// 平方根: Sqrt(4) => 2, Sqrt(NewRat(1, 4)) => 1/2, Sqrt(2) => 1.414…
+// 非負の正確数で分子と分母がともに平方数ならば正確数を返す。
+// 負数に対しては NaN を返す。
+func Sqrt(a Number) Number {
+	if IsRational(a) {
+		x := toRat(a)
+		if x.Sign() >= 0 {
+			n := exactSqrt(x.Num())
+			d := exactSqrt(x.Denom())
+			if n != nil && d != nil {
+				return regulateRat(new(Rat).SetFrac(n, d))
+			}
+		}
+	}
+	return math.Sqrt(Float64(a))
+}
+
+// 指数関数 e^a: Exp(0) => 1, Exp(1) => 2.718…
+func Exp(a Number) Number {
+	if isExact(a, 0) {
+		return int32(1)
+	}
+	return math.Exp(Float64(a))
+}
+
+// 自然対数: Log(1) => 0, Log(E) => 1.0
+// float64 の範囲を越える正確数でも対数を求めることができる。
+// ゼロに対しては -Inf を，負数に対しては NaN を返す。
+func Log(a Number) Number {
+	if isExact(a, 1) {
+		return int32(0)
+	}
+	if IsRational(a) {
+		x := toRat(a)
+		if x.Sign() > 0 {
+			// x = mant × 2^exp (0.5 <= mant < 1) として log を求める。
+			f := new(Float).SetPrec(64).SetRat(x)
+			mant := new(Float)
+			exp := f.MantExp(mant)
+			m, _ := mant.Float64()
+			return math.Log(m) + float64(exp)*math.Ln2
+		}
+	}
+	return math.Log(Float64(a))
+}
+
+// 底を b とする対数: LogBase(8, 2) => 3
+// a と b が正確数で a が b の整数乗ならば正確数を返す。
+// 底が 1 ならば不正確数の底と同じく無限大または NaN を返す。
+func LogBase(a, b Number) Number {
+	if isExact(b, 1) {
+		return LogBase(a, Float64(b))
+	}
+	r := DivideReal(Log(a), Log(b))
+	if IsRational(a) && IsRational(b) && !IsNaN(r) && !IsInf(r) {
+		k := RoundNumber(r)
+		if IsInteger(k) && Compare(Abs(k), 1<<20) < 0 &&
+			Compare(Expt(b, k), a) == 0 {
+			return k
+		}
+	}
+	return r
+}
+
+// 正弦: Sin(0) => 0
+func Sin(a Number) Number {
+	if isExact(a, 0) {
+		return int32(0)
+	}
+	return math.Sin(Float64(a))
+}
+
+// 余弦: Cos(0) => 1
+func Cos(a Number) Number {
+	if isExact(a, 0) {
+		return int32(1)
+	}
+	return math.Cos(Float64(a))
+}
+
+// 正接: Tan(0) => 0
+func Tan(a Number) Number {
+	if isExact(a, 0) {
+		return int32(0)
+	}
+	return math.Tan(Float64(a))
+}
+
+// 逆正弦: Asin(0) => 0
+func Asin(a Number) Number {
+	if isExact(a, 0) {
+		return int32(0)
+	}
+	return math.Asin(Float64(a))
+}
+
+// 逆余弦: Acos(1) => 0
+func Acos(a Number) Number {
+	if isExact(a, 1) {
+		return int32(0)
+	}
+	return math.Acos(Float64(a))
+}
+
+// 逆正接: Atan(0) => 0
+func Atan(a Number) Number {
+	if isExact(a, 0) {
+		return int32(0)
+	}
+	return math.Atan(Float64(a))
+}
+
+// 点 (x, y) の偏角 (y/x の逆正接): Atan2(1, 1) => 0.785…
+func Atan2(y, x Number) Number {
+	if isExact(y, 0) && IsRational(x) && Sign(x) > 0 {
+		return int32(0)
+	}
+	return math.Atan2(Float64(y), Float64(x))
+}
+
+// 双曲線正弦: Sinh(0) => 0
+func Sinh(a Number) Number {
+	if isExact(a, 0) {
+		return int32(0)
+	}
+	return math.Sinh(Float64(a))
+}
+
+// 双曲線余弦: Cosh(0) => 1
+func Cosh(a Number) Number {
+	if isExact(a, 0) {
+		return int32(1)
+	}
+	return math.Cosh(Float64(a))
+}
+
+// 双曲線正接: Tanh(0) => 0
+func Tanh(a Number) Number {
+	if isExact(a, 0) {
+		return int32(0)
+	}
+	return math.Tanh(Float64(a))
+}
+
+// float64 の文字列表現を解釈する。+inf.0, -inf.0, +nan.0 も受け付ける。
+// 範囲を越える値は ±Inf または ±0 になる。
+func ParseFloat64(s string) (float64, error) {
+	switch s {
+	case "+inf.0":
+		return math.Inf(1), nil
+	case "-inf.0":
+		return math.Inf(-1), nil
+	case "+nan.0", "-nan.0":
+		return math.NaN(), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if e, ok := err.(*strconv.NumError); ok && e.Err != strconv.ErrRange {
+		return 0, Errorf("invalid float: %q", s)
+	}
+	return f, nil
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
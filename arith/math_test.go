@@ -0,0 +1,253 @@
+// H25.4/26 (鈴)
+
+package arith
+
+import (
+	. "fmt"
+	"math"
+	. "math/big"
+)
+
+func ExampleIsNaN() {
+	Println(IsNaN(math.NaN()), IsNaN(math.Inf(1)), IsNaN(7))
+	// Output:
+	// true false false
+}
+
+func ExampleIsInf() {
+	Println(IsInf(math.Inf(1)), IsInf(math.Inf(-1)), IsInf(math.NaN()),
+		IsInf(7))
+	// Output:
+	// true true false false
+}
+
+func ExampleSqrt() {
+	r := Sqrt(4)
+	Printf("%T %v\n", r, r)
+	r = Sqrt(NewRat(1, 4))
+	Printf("%T %v\n", r, r)
+	r = Sqrt(2)
+	Printf("%T %v\n", r, r)
+	r = Sqrt(4.0)
+	Printf("%T %v\n", r, r)
+	r = Sqrt(-4)
+	Printf("%T %v\n", r, r)
+	r = Sqrt(Expt(10, 40))
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 2
+	// *big.Rat 1/2
+	// float64 1.4142135623730951
+	// float64 2
+	// float64 NaN
+	// *big.Int 100000000000000000000
+}
+
+func ExampleExp() {
+	r := Exp(0)
+	Printf("%T %v\n", r, r)
+	r = Exp(1)
+	Printf("%T %v\n", r, r)
+	r = Exp(0.0)
+	Printf("%T %v\n", r, r)
+	r = Exp(1000)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 1
+	// float64 2.718281828459045
+	// float64 1
+	// float64 +Inf
+}
+
+func ExampleLog() {
+	r := Log(1)
+	Printf("%T %v\n", r, r)
+	r = Log(E)
+	Printf("%T %v\n", r, r)
+	r = Log(0)
+	Printf("%T %v\n", r, r)
+	r = Log(-1)
+	Printf("%T %v\n", r, r)
+	r = Log(Expt(10, 400))
+	Printf("%T %.6f\n", r, r)
+	r = Log(NewRat(1, 2))
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 0
+	// float64 1
+	// float64 -Inf
+	// float64 NaN
+	// float64 921.034037
+	// float64 -0.6931471805599453
+}
+
+func ExampleLogBase() {
+	r := LogBase(8, 2)
+	Printf("%T %v\n", r, r)
+	r = LogBase(NewRat(1, 1000), 10)
+	Printf("%T %v\n", r, r)
+	r = LogBase(10, 2)
+	Printf("%T %v\n", r, r)
+	r = LogBase(8.0, 2)
+	Printf("%T %v\n", r, r)
+	r = LogBase(8, 1)
+	Printf("%T %v\n", r, r)
+	r = LogBase(1, 1)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 3
+	// int32 -3
+	// float64 3.321928094887362
+	// float64 3
+	// float64 +Inf
+	// float64 NaN
+}
+
+func ExampleSin() {
+	r := Sin(0)
+	Printf("%T %v\n", r, r)
+	r = Sin(DivideReal(Pi, 2))
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 0
+	// float64 1
+}
+
+func ExampleCos() {
+	r := Cos(0)
+	Printf("%T %v\n", r, r)
+	r = Cos(Pi)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 1
+	// float64 -1
+}
+
+func ExampleTan() {
+	r := Tan(0)
+	Printf("%T %v\n", r, r)
+	r = Tan(DivideReal(Pi, 4))
+	Printf("%T %.6f\n", r, r)
+	// Output:
+	// int32 0
+	// float64 1.000000
+}
+
+func ExampleAsin() {
+	r := Asin(0)
+	Printf("%T %v\n", r, r)
+	r = Asin(1)
+	Printf("%T %v\n", r, r)
+	r = Asin(2)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 0
+	// float64 1.5707963267948966
+	// float64 NaN
+}
+
+func ExampleAcos() {
+	r := Acos(1)
+	Printf("%T %v\n", r, r)
+	r = Acos(0)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 0
+	// float64 1.5707963267948966
+}
+
+func ExampleAtan() {
+	r := Atan(0)
+	Printf("%T %v\n", r, r)
+	r = Atan(1)
+	Printf("%T %v\n", r, r)
+	r = Atan(math.Inf(1))
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 0
+	// float64 0.7853981633974483
+	// float64 1.5707963267948966
+}
+
+func ExampleAtan2() {
+	r := Atan2(0, 1)
+	Printf("%T %v\n", r, r)
+	r = Atan2(1, 1)
+	Printf("%T %v\n", r, r)
+	r = Atan2(0, -1)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 0
+	// float64 0.7853981633974483
+	// float64 3.141592653589793
+}
+
+func ExampleSinh() {
+	r := Sinh(0)
+	Printf("%T %v\n", r, r)
+	r = Sinh(1)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 0
+	// float64 1.1752011936438014
+}
+
+func ExampleCosh() {
+	r := Cosh(0)
+	Printf("%T %v\n", r, r)
+	r = Cosh(1)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 1
+	// float64 1.5430806348152437
+}
+
+func ExampleTanh() {
+	r := Tanh(0)
+	Printf("%T %v\n", r, r)
+	r = Tanh(1)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 0
+	// float64 0.7615941559557649
+}
+
+func ExampleParseFloat64() {
+	for _, s := range []string{"1.5", "7e20", "1e400", "-1e400", "1e-400",
+		"+inf.0", "-inf.0", "+nan.0", "1.5x"} {
+		r, err := ParseFloat64(s)
+		Println(String(r), err)
+	}
+	// Output:
+	// 1.5 <nil>
+	// 7e+20 <nil>
+	// +inf.0 <nil>
+	// -inf.0 <nil>
+	// 0.0 <nil>
+	// +inf.0 <nil>
+	// -inf.0 <nil>
+	// +nan.0 <nil>
+	// 0.0 invalid float: "1.5x"
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -1,10 +1,12 @@
 // H25.1/16 - H25.1/21 (鈴)
 
 /*
- パッケージ arith は int32, float64, Rat ("math/big" の無限多倍長有理数)
- の自動的な相互変換を伴う算術演算を実装する.
- int32 の演算を内部的に int64 で行うことで桁あふれ時の Rat への自動的な
- 変換を実現する.
+パッケージ arith は int32, float64, Int ("math/big" の無限多倍長整数),
+Rat ("math/big" の無限多倍長有理数) の自動的な相互変換を伴う算術演算を
+実装する.
+int32 の演算を内部的に int64 で行うことで桁あふれ時の Int への自動的な
+変換を実現する. 整数値の Rat は Int または int32 に, int32 に収まる
+Int は int32 に戻す.
 */
 package arith
 
@@ -16,50 +18,69 @@ import (
 	"strings"
 )
 
-// Number は int32, float64 または *Rat を表す型であるとする
+// Number は int32, float64, *Int または *Rat を表す型であるとする
 // (実際の型検査は実行時に行う)。
-// ただし，関数の引数としては便宜のため int, int64 も許す
-// (それらは実行時の値によって int32 または *Rat へと変換される)。
+// *Int の値は常に int32 の範囲外であり, *Rat の値は常に整数でない。
+// ただし，関数の引数としては便宜のため int, int64 も許し，また上記の
+// 範囲に正規化されていない *Int, *Rat も許す
+// (それらは実行時の値によって適切な型へと変換される)。
 type Number interface{}
 
-// Number 引数を int32, float64, *Rat のどれかに当てはめる。
+// Number 引数を int32, float64, *Int, *Rat のどれかに当てはめる。
 func regulateArg(a Number) Number {
 	switch x := a.(type) {
 	case int:
-		var i int32 = int32(x)
-		if int(i) == x {
-			return i
-		}
-		return NewRat(int64(x), 1)
+		return regulateInt64(int64(x))
 	case int64:
 		return regulateInt64(x)
 	case int32:
 		return x
 	case float64:
 		return x
+	case *Int:
+		return regulateInt(x)
 	case *Rat:
-		return x
+		return regulateRat(x)
 	}
 	panic(Errorf("unsupported type: %T", a))
 }
 
-// int64 の値を可能ならば int32 の値に，できなければ有理数にする。
+// int64 の値を可能ならば int32 の値に，できなければ多倍長整数にする。
 func regulateInt64(a int64) Number {
 	var i int32 = int32(a)
 	if int64(i) == a {
 		return i
 	}
-	return NewRat(a, 1)
+	return NewInt(a)
 }
 
-// 有理数を float64 の値にする。
-// もしも範囲を越えるときは *strconv.NumError で panic する。
-func ratToFloat64(a *Rat) float64 {
-	s := a.FloatString(17)
-	f, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		panic(err)
+// 多倍長整数を可能ならば int32 の値にする。
+func regulateInt(a *Int) Number {
+	if a.BitLen() < 32 { // 符号ビットを除き 31 ビット長以内か？
+		return int32(a.Int64())
+	}
+	return a
+}
+
+// 有理数を可能ならば int32 または多倍長整数の値にする。
+func regulateRat(a *Rat) Number {
+	if a.IsInt() {
+		return regulateInt(new(Int).Set(a.Num()))
 	}
+	return a
+}
+
+// 有理数を最も近い float64 の値にする。
+// もしも範囲を越えるときは符号に応じて +Inf または -Inf を返す。
+func ratToFloat64(a *Rat) float64 {
+	f, _ := a.Float64()
+	return f
+}
+
+// 多倍長整数を最も近い float64 の値にする。
+// もしも範囲を越えるときは符号に応じて +Inf または -Inf を返す。
+func intToFloat64(a *Int) float64 {
+	f, _ := new(Float).SetInt(a).Float64()
 	return f
 }
 
@@ -69,12 +90,11 @@ func truncateFloat64(a float64) Number {
 	if math.MinInt32-1 < a && a < math.MaxInt32+1 {
 		return int32(a)
 	}
-	s := strconv.FormatFloat(a, 'g', -1, 64)
-	r, ok := new(Rat).SetString(s)
-	if !ok {
+	if math.IsInf(a, 0) || math.IsNaN(a) {
 		return a
 	}
-	return truncateRat(r)
+	n, _ := NewFloat(a).Int(nil) // これはゼロの方向へ丸める
+	return regulateInt(n)
 }
 
 // float64 に対する符号関数
@@ -87,37 +107,35 @@ func signum(a float64) int {
 	return 1
 }
 
-// 有理数を可能ならば int32 の値にする。
-func regulateRat(a *Rat) Number {
-	if a.IsInt() {
-		n := a.Num()
-		if n.BitLen() < 32 { // 符号ビットを除き 31 ビット長以内か？
-			return int32(n.Int64())
-		}
+// float64 どうしの比較. 差をとらないので無限大どうしも比較できる。
+// NaN との比較は 1 を返すから，呼び出し側で IsNaN を調べること。
+func compareFloat64(a, b float64) int {
+	if a < b {
+		return -1
+	} else if a == b {
+		return 0
 	}
-	return a
+	return 1
 }
 
 // 有理数をゼロの方向へ丸めた整数を返す。
 func truncateRat(a *Rat) Number {
 	n := a.Num()
 	d := a.Denom()
-	q := new(Int).Quo(n, d)
-	if q.BitLen() < 32 { // 符号ビットを除き 31 ビット長以内か？
-		return int32(q.Int64())
-	}
-	return new(Rat).SetInt(q)
+	return regulateInt(new(Int).Quo(n, d))
 }
 
 // 以下，公開関数
 
-// int32, float64 または *Rat ならば true を返す。
+// int32, float64, *Int または *Rat ならば true を返す。
 func IsNumber(a interface{}) bool {
 	switch a.(type) {
 	case int32:
 		return true
 	case float64:
 		return true
+	case *Int:
+		return true
 	case *Rat:
 		return true
 	}
@@ -133,6 +151,9 @@ func Add(a, b Number) Number {
 			return regulateInt64(int64(x) + int64(y))
 		case float64:
 			return float64(x) + y
+		case *Int:
+			z := NewInt(int64(x))
+			return regulateInt(z.Add(z, y))
 		case *Rat:
 			z := NewRat(int64(x), 1)
 			return regulateRat(z.Add(z, y))
@@ -143,9 +164,24 @@ func Add(a, b Number) Number {
 			return x + float64(y)
 		case float64:
 			return x + y
+		case *Int:
+			return x + intToFloat64(y)
 		case *Rat:
 			return x + ratToFloat64(y)
 		}
+	case *Int:
+		switch y := b.(type) {
+		case int32:
+			z := NewInt(int64(y))
+			return regulateInt(z.Add(x, z))
+		case float64:
+			return intToFloat64(x) + y
+		case *Int:
+			return regulateInt(new(Int).Add(x, y))
+		case *Rat:
+			z := new(Rat).SetInt(x)
+			return regulateRat(z.Add(z, y))
+		}
 	case *Rat:
 		switch y := b.(type) {
 		case int32:
@@ -153,6 +189,9 @@ func Add(a, b Number) Number {
 			return regulateRat(z.Add(x, z))
 		case float64:
 			return ratToFloat64(x) + y
+		case *Int:
+			z := new(Rat).SetInt(y)
+			return regulateRat(z.Add(x, z))
 		case *Rat:
 			return regulateRat(new(Rat).Add(x, y))
 		}
@@ -169,6 +208,9 @@ func Subtract(a, b Number) Number {
 			return regulateInt64(int64(x) - int64(y))
 		case float64:
 			return float64(x) - y
+		case *Int:
+			z := NewInt(int64(x))
+			return regulateInt(z.Sub(z, y))
 		case *Rat:
 			z := NewRat(int64(x), 1)
 			return regulateRat(z.Sub(z, y))
@@ -179,9 +221,24 @@ func Subtract(a, b Number) Number {
 			return x - float64(y)
 		case float64:
 			return x - y
+		case *Int:
+			return x - intToFloat64(y)
 		case *Rat:
 			return x - ratToFloat64(y)
 		}
+	case *Int:
+		switch y := b.(type) {
+		case int32:
+			z := NewInt(int64(y))
+			return regulateInt(z.Sub(x, z))
+		case float64:
+			return intToFloat64(x) - y
+		case *Int:
+			return regulateInt(new(Int).Sub(x, y))
+		case *Rat:
+			z := new(Rat).SetInt(x)
+			return regulateRat(z.Sub(z, y))
+		}
 	case *Rat:
 		switch y := b.(type) {
 		case int32:
@@ -189,6 +246,9 @@ func Subtract(a, b Number) Number {
 			return regulateRat(z.Sub(x, z))
 		case float64:
 			return ratToFloat64(x) - y
+		case *Int:
+			z := new(Rat).SetInt(y)
+			return regulateRat(z.Sub(x, z))
 		case *Rat:
 			return regulateRat(new(Rat).Sub(x, y))
 		}
@@ -205,6 +265,9 @@ func Multiply(a, b Number) Number {
 			return regulateInt64(int64(x) * int64(y))
 		case float64:
 			return float64(x) * y
+		case *Int:
+			z := NewInt(int64(x))
+			return regulateInt(z.Mul(z, y))
 		case *Rat:
 			z := NewRat(int64(x), 1)
 			return regulateRat(z.Mul(z, y))
@@ -215,9 +278,24 @@ func Multiply(a, b Number) Number {
 			return x * float64(y)
 		case float64:
 			return x * y
+		case *Int:
+			return x * intToFloat64(y)
 		case *Rat:
 			return x * ratToFloat64(y)
 		}
+	case *Int:
+		switch y := b.(type) {
+		case int32:
+			z := NewInt(int64(y))
+			return regulateInt(z.Mul(x, z))
+		case float64:
+			return intToFloat64(x) * y
+		case *Int:
+			return regulateInt(new(Int).Mul(x, y))
+		case *Rat:
+			z := new(Rat).SetInt(x)
+			return regulateRat(z.Mul(z, y))
+		}
 	case *Rat:
 		switch y := b.(type) {
 		case int32:
@@ -225,6 +303,9 @@ func Multiply(a, b Number) Number {
 			return regulateRat(z.Mul(x, z))
 		case float64:
 			return ratToFloat64(x) * y
+		case *Int:
+			z := new(Rat).SetInt(y)
+			return regulateRat(z.Mul(x, z))
 		case *Rat:
 			return regulateRat(new(Rat).Mul(x, y))
 		}
@@ -241,6 +322,8 @@ func DivideReal(a, b Number) Number {
 			return regulateRat(NewRat(int64(x), int64(y)))
 		case float64:
 			return float64(x) / y
+		case *Int:
+			return regulateRat(new(Rat).SetFrac(NewInt(int64(x)), y))
 		case *Rat:
 			z := NewRat(int64(x), 1)
 			return regulateRat(z.Quo(z, y))
@@ -251,9 +334,23 @@ func DivideReal(a, b Number) Number {
 			return x / float64(y)
 		case float64:
 			return x / y
+		case *Int:
+			return x / intToFloat64(y)
 		case *Rat:
 			return x / ratToFloat64(y)
 		}
+	case *Int:
+		switch y := b.(type) {
+		case int32:
+			return regulateRat(new(Rat).SetFrac(x, NewInt(int64(y))))
+		case float64:
+			return intToFloat64(x) / y
+		case *Int:
+			return regulateRat(new(Rat).SetFrac(x, y))
+		case *Rat:
+			z := new(Rat).SetInt(x)
+			return regulateRat(z.Quo(z, y))
+		}
 	case *Rat:
 		switch y := b.(type) {
 		case int32:
@@ -261,6 +358,9 @@ func DivideReal(a, b Number) Number {
 			return regulateRat(z.Quo(x, z))
 		case float64:
 			return ratToFloat64(x) / y
+		case *Int:
+			z := new(Rat).SetInt(y)
+			return regulateRat(z.Quo(x, z))
 		case *Rat:
 			return regulateRat(new(Rat).Quo(x, y))
 		}
@@ -270,6 +370,7 @@ func DivideReal(a, b Number) Number {
 
 // 整数除算: DivideInt(7, 2) => 3
 func DivideInt(a, b Number) Number {
+	checkDivisor(a, b)
 	switch x := a.(type) {
 	case int32:
 		switch y := b.(type) {
@@ -277,6 +378,9 @@ func DivideInt(a, b Number) Number {
 			return regulateInt64(int64(x) / int64(y))
 		case float64:
 			return truncateFloat64(float64(x) / y)
+		case *Int:
+			z := NewInt(int64(x))
+			return regulateInt(z.Quo(z, y))
 		case *Rat:
 			z := NewRat(int64(x), 1)
 			return truncateRat(z.Quo(z, y))
@@ -287,9 +391,24 @@ func DivideInt(a, b Number) Number {
 			return truncateFloat64(x / float64(y))
 		case float64:
 			return truncateFloat64(x / y)
+		case *Int:
+			return truncateFloat64(x / intToFloat64(y))
 		case *Rat:
 			return truncateFloat64(x / ratToFloat64(y))
 		}
+	case *Int:
+		switch y := b.(type) {
+		case int32:
+			z := NewInt(int64(y))
+			return regulateInt(z.Quo(x, z))
+		case float64:
+			return truncateFloat64(intToFloat64(x) / y)
+		case *Int:
+			return regulateInt(new(Int).Quo(x, y))
+		case *Rat:
+			z := new(Rat).SetInt(x)
+			return truncateRat(z.Quo(z, y))
+		}
 	case *Rat:
 		switch y := b.(type) {
 		case int32:
@@ -297,6 +416,9 @@ func DivideInt(a, b Number) Number {
 			return truncateRat(z.Quo(x, z))
 		case float64:
 			return truncateFloat64(ratToFloat64(x) / y)
+		case *Int:
+			z := new(Rat).SetInt(y)
+			return truncateRat(z.Quo(x, z))
 		case *Rat:
 			return truncateRat(new(Rat).Quo(x, y))
 		}
@@ -319,7 +441,9 @@ func Compare(a, b Number) int {
 				return 1
 			}
 		case float64:
-			return signum(float64(x) - y)
+			return compareFloat64(float64(x), y)
+		case *Int: // y は正規化されていないかもしれない
+			return NewInt(int64(x)).Cmp(y)
 		case *Rat:
 			z := NewRat(int64(x), 1)
 			return z.Cmp(y)
@@ -327,11 +451,24 @@ func Compare(a, b Number) int {
 	case float64:
 		switch y := b.(type) {
 		case int32:
-			return signum(x - float64(y))
+			return compareFloat64(x, float64(y))
+		case float64:
+			return compareFloat64(x, y)
+		case *Int:
+			return compareFloat64(x, intToFloat64(y))
+		case *Rat:
+			return compareFloat64(x, ratToFloat64(y))
+		}
+	case *Int:
+		switch y := b.(type) {
+		case int32: // x は正規化されていないかもしれない
+			return x.Cmp(NewInt(int64(y)))
 		case float64:
-			return signum(x - y)
+			return compareFloat64(intToFloat64(x), y)
+		case *Int:
+			return x.Cmp(y)
 		case *Rat:
-			return signum(x - ratToFloat64(y))
+			return new(Rat).SetInt(x).Cmp(y)
 		}
 	case *Rat:
 		switch y := b.(type) {
@@ -339,7 +476,9 @@ func Compare(a, b Number) int {
 			z := NewRat(int64(y), 1)
 			return x.Cmp(z)
 		case float64:
-			return signum(ratToFloat64(x) - y)
+			return compareFloat64(ratToFloat64(x), y)
+		case *Int:
+			return x.Cmp(new(Rat).SetInt(y))
 		case *Rat:
 			return x.Cmp(y)
 		}
@@ -354,6 +493,8 @@ func Float64(a Number) float64 {
 		return float64(x)
 	case float64:
 		return x
+	case *Int:
+		return intToFloat64(x)
 	case *Rat:
 		return ratToFloat64(x)
 	}
@@ -367,6 +508,8 @@ func Truncate(a Number) Number {
 		return x
 	case float64:
 		return truncateFloat64(x)
+	case *Int:
+		return regulateInt(x)
 	case *Rat:
 		return truncateRat(x)
 	}
@@ -374,16 +517,26 @@ func Truncate(a Number) Number {
 }
 
 // 数の文字列表現を得る。String(7.0) => "7.0"
+// 無限大と NaN は +inf.0, -inf.0, +nan.0 と表す。
 func String(a Number) string {
 	switch x := a.(type) {
 	case int32:
 		return strconv.FormatInt(int64(x), 10)
 	case float64:
+		if math.IsNaN(x) {
+			return "+nan.0"
+		} else if math.IsInf(x, 1) {
+			return "+inf.0"
+		} else if math.IsInf(x, -1) {
+			return "-inf.0"
+		}
 		s := strconv.FormatFloat(x, 'g', -1, 64)
 		if !strings.ContainsAny(s, ".e") {
 			s = s + ".0"
 		}
 		return s
+	case *Int:
+		return x.String()
 	case *Rat:
 		if x.IsInt() {
 			return x.Num().String()
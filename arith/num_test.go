@@ -6,6 +6,7 @@ import (
 	. "fmt"
 	"math"
 	. "math/big"
+	"testing"
 )
 
 func ExampleIsNumber() {
@@ -14,12 +15,14 @@ func ExampleIsNumber() {
 	Println(IsNumber(NewRat(7, 2)))
 	Println(IsNumber("7"))
 	Println(IsNumber(int64(7)))
+	Println(IsNumber(NewInt(7)))
 	// Output:
 	// true
 	// true
 	// true
 	// false
 	// false
+	// true
 }
 
 func ExampleAdd() {
@@ -43,6 +46,16 @@ func ExampleAdd() {
 	Printf("%T %v\n", r, r)
 	r = Add(NewRat(7, 2), NewRat(2, 3))
 	Printf("%T %v\n", r, r)
+	r = Add(7, new(Int).Lsh(NewInt(1), 40))
+	Printf("%T %v\n", r, r)
+	r = Add(new(Int).Lsh(NewInt(1), 40), 2.0)
+	Printf("%T %v\n", r, r)
+	r = Add(new(Int).Lsh(NewInt(1), 40), NewRat(1, 2))
+	Printf("%T %v\n", r, r)
+	r = Add(2147483647, 1)
+	Printf("%T %v\n", r, r)
+	r = Add(NewInt(2147483648), -1)
+	Printf("%T %v\n", r, r)
 	// Output:
 	// int32 9
 	// float64 9
@@ -54,6 +67,11 @@ func ExampleAdd() {
 	// float64 9
 	// int32 9
 	// *big.Rat 25/6
+	// *big.Int 1099511627783
+	// float64 1.099511627778e+12
+	// *big.Rat 2199023255553/2
+	// *big.Int 2147483648
+	// int32 2147483647
 }
 
 func ExampleSubtract() {
@@ -77,6 +95,14 @@ func ExampleSubtract() {
 	Printf("%T %v\n", r, r)
 	r = Subtract(NewRat(7, 2), NewRat(2, 3))
 	Printf("%T %v\n", r, r)
+	r = Subtract(new(Int).Lsh(NewInt(1), 40), 2)
+	Printf("%T %v\n", r, r)
+	r = Subtract(7.0, new(Int).Lsh(NewInt(1), 40))
+	Printf("%T %v\n", r, r)
+	r = Subtract(new(Int).Lsh(NewInt(1), 40), new(Int).Lsh(NewInt(1), 40))
+	Printf("%T %v\n", r, r)
+	r = Subtract(-2147483648, 1)
+	Printf("%T %v\n", r, r)
 	// Output:
 	// int32 5
 	// float64 5
@@ -88,6 +114,10 @@ func ExampleSubtract() {
 	// float64 5
 	// int32 5
 	// *big.Rat 17/6
+	// *big.Int 1099511627774
+	// float64 -1.099511627769e+12
+	// int32 0
+	// *big.Int -2147483649
 }
 
 func ExampleMultiply() {
@@ -111,6 +141,14 @@ func ExampleMultiply() {
 	Printf("%T %v\n", r, r)
 	r = Multiply(NewRat(7, 2), NewRat(2, 3))
 	Printf("%T %v\n", r, r)
+	r = Multiply(new(Int).Lsh(NewInt(1), 40), 2)
+	Printf("%T %v\n", r, r)
+	r = Multiply(NewRat(1, 2), new(Int).Lsh(NewInt(1), 40))
+	Printf("%T %v\n", r, r)
+	r = Multiply(new(Int).Lsh(NewInt(1), 40), 0.5)
+	Printf("%T %v\n", r, r)
+	r = Multiply(65536, 65536)
+	Printf("%T %v\n", r, r)
 	// Output:
 	// int32 14
 	// float64 14
@@ -122,6 +160,10 @@ func ExampleMultiply() {
 	// float64 14
 	// int32 14
 	// *big.Rat 7/3
+	// *big.Int 2199023255552
+	// *big.Int 549755813888
+	// float64 5.49755813888e+11
+	// *big.Int 4294967296
 }
 
 func ExampleDivideReal() {
@@ -145,6 +187,12 @@ func ExampleDivideReal() {
 	Printf("%T %v\n", r, r)
 	r = DivideReal(NewRat(7, 2), NewRat(2, 3))
 	Printf("%T %v\n", r, r)
+	r = DivideReal(new(Int).Lsh(NewInt(1), 40), 2)
+	Printf("%T %v\n", r, r)
+	r = DivideReal(7, new(Int).Lsh(NewInt(1), 40))
+	Printf("%T %v\n", r, r)
+	r = DivideReal(new(Int).Lsh(NewInt(1), 40), new(Int).Lsh(NewInt(1), 40))
+	Printf("%T %v\n", r, r)
 	// Output:
 	// *big.Rat 7/2
 	// float64 3.5
@@ -156,6 +204,9 @@ func ExampleDivideReal() {
 	// float64 3.5
 	// *big.Rat 7/2
 	// *big.Rat 21/4
+	// *big.Int 549755813888
+	// *big.Rat 7/1099511627776
+	// int32 1
 }
 
 func ExampleDivideInt() {
@@ -179,6 +230,12 @@ func ExampleDivideInt() {
 	Printf("%T %v\n", r, r)
 	r = DivideInt(NewRat(7, 2), NewRat(2, 3))
 	Printf("%T %v\n", r, r)
+	r = DivideInt(new(Int).Lsh(NewInt(1), 40), 3)
+	Printf("%T %v\n", r, r)
+	r = DivideInt(7, new(Int).Lsh(NewInt(1), 40))
+	Printf("%T %v\n", r, r)
+	r = DivideInt(new(Int).Lsh(NewInt(1), 40), NewRat(1, 2))
+	Printf("%T %v\n", r, r)
 	// Output:
 	// int32 3
 	// int32 3
@@ -190,6 +247,9 @@ func ExampleDivideInt() {
 	// int32 3
 	// int32 3
 	// int32 5
+	// *big.Int 366503875925
+	// int32 0
+	// *big.Int 2199023255552
 }
 
 func ExampleCompare() {
@@ -219,6 +279,18 @@ func ExampleCompare() {
 	Printf("%T %v\n", r, r)
 	r = Compare(NewRat(2, 3), NewRat(7, 2))
 	Printf("%T %v\n", r, r)
+	r = Compare(math.Inf(1), math.Inf(1))
+	Printf("%T %v\n", r, r)
+	r = Compare(math.Inf(-1), 7)
+	Printf("%T %v\n", r, r)
+	r = Compare(new(Int).Lsh(NewInt(1), 40), 7)
+	Printf("%T %v\n", r, r)
+	r = Compare(-7, new(Int).Lsh(NewInt(1), 40))
+	Printf("%T %v\n", r, r)
+	r = Compare(new(Int).Lsh(NewInt(1), 40), 1e12)
+	Printf("%T %v\n", r, r)
+	r = Compare(new(Int).Lsh(NewInt(1), 40), NewRat(1, 2))
+	Printf("%T %v\n", r, r)
 	// Output:
 	// int 0
 	// int 1
@@ -233,6 +305,28 @@ func ExampleCompare() {
 	// int 0
 	// int 1
 	// int -1
+	// int 0
+	// int -1
+	// int 1
+	// int -1
+	// int 1
+	// int 1
+}
+
+func ExampleCompare_int() {
+	r := Compare(int32(5), NewInt(3))
+	Printf("%T %v\n", r, r)
+	r = Compare(NewInt(3), int32(5))
+	Printf("%T %v\n", r, r)
+	r = Compare(int32(-5), NewInt(-5))
+	Printf("%T %v\n", r, r)
+	r = Compare(int32(5), new(Int).Lsh(NewInt(-1), 40))
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int 1
+	// int -1
+	// int 0
+	// int 1
 }
 
 func ExampleFloat64() {
@@ -244,11 +338,18 @@ func ExampleFloat64() {
 	Printf("%T %v\n", r, r)
 	r = Float64(NewRat(-7, 2))
 	Printf("%T %v\n", r, r)
+	z := new(Rat).SetInt(new(Int).Exp(NewInt(10), NewInt(400), nil))
+	r = Float64(z)
+	Printf("%T %v\n", r, r)
+	r = Float64(z.Neg(z))
+	Printf("%T %v\n", r, r)
 	// Output:
 	// float64 7
 	// float64 7
 	// float64 7
 	// float64 -3.5
+	// float64 +Inf
+	// float64 -Inf
 }
 
 func ExampleTruncate() {
@@ -287,13 +388,13 @@ func ExampleTruncate() {
 	// int32 7
 	// int32 7
 	// int32 3
-	// *big.Rat 3500000000000/1
+	// *big.Int 3500000000000
 	// int32 -3
 	// int32 -3
 	// int32 2147483647
-	// *big.Rat 2147483648/1
+	// *big.Int 2147483648
 	// int32 -2147483648
-	// *big.Rat -2147483649/1
+	// *big.Int -2147483649
 	// float64 +Inf
 }
 
@@ -321,6 +422,12 @@ func ExampleString() {
 	z, _ := new(Rat).SetString("123456789012345678901234567890")
 	r = String(z)
 	Printf("%T %v\n", r, r)
+	r = String(math.Inf(1))
+	Printf("%T %v\n", r, r)
+	r = String(math.Inf(-1))
+	Printf("%T %v\n", r, r)
+	r = String(math.NaN())
+	Printf("%T %v\n", r, r)
 	// Output:
 	// string 7
 	// string 7
@@ -333,6 +440,9 @@ func ExampleString() {
 	// string -7.0
 	// string -7.2
 	// string 123456789012345678901234567890
+	// string +inf.0
+	// string -inf.0
+	// string +nan.0
 }
 
 func ExampleNumber_factorial() {
@@ -373,6 +483,56 @@ func ExampleNumber_factorial() {
 	// 8841761993739701954543616000000
 }
 
+func ExampleNumber_fibonacci() {
+	var a, b Number = 0, 1
+	for i := 0; i < 100; i++ {
+		a, b = b, Add(a, b)
+	}
+	Printf("%T %v\n", a, a)
+	// Output:
+	// *big.Int 354224848179261915075
+}
+
+// 階乗の計算. 多倍長整数は *Int のまま乗算される。
+func BenchmarkFactorial(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var n Number = 1
+		for j := 1; j <= 1000; j++ {
+			n = Multiply(n, j)
+		}
+	}
+}
+
+// 比較のため *Rat で階乗を計算する (以前の多倍長整数の表現)。
+func BenchmarkFactorialRat(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		n := NewRat(1, 1)
+		for j := 1; j <= 1000; j++ {
+			n.Mul(n, NewRat(int64(j), 1))
+		}
+	}
+}
+
+// フィボナッチ数の計算
+func BenchmarkFibonacci(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var x, y Number = 0, 1
+		for j := 0; j < 5000; j++ {
+			x, y = y, Add(x, y)
+		}
+	}
+}
+
+// 比較のため *Rat でフィボナッチ数を計算する (以前の多倍長整数の表現)。
+func BenchmarkFibonacciRat(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		x, y := NewRat(0, 1), NewRat(1, 1)
+		for j := 0; j < 5000; j++ {
+			x, y = y, new(Rat).Add(x, y)
+		}
+	}
+}
+
 /*
   Copyright (c) 2013 OKI Software Co., Ltd.
 
@@ -0,0 +1,326 @@
+// H25.4/24 (鈴)
+
+// このファイルは整数除算の剰余，丸め，最大公約数，累乗，
+// 正確数と不正確数の変換などの算術演算を実装する。
+
+package arith
+
+import (
+	. "fmt"
+	"math"
+	. "math/big"
+)
+
+// 正確数 (int32, *Int または *Rat) を *Rat にする。
+// 不正確数 (float64) ならば panic する。
+func toRat(a Number) *Rat {
+	switch x := regulateArg(a).(type) {
+	case int32:
+		return NewRat(int64(x), 1)
+	case *Int:
+		return new(Rat).SetInt(x)
+	case *Rat:
+		return x
+	}
+	panic(Errorf("exact number expected: %v", a))
+}
+
+// 整数 (int32 または *Int) を *Int にする。
+// 整数でなければ panic する。
+func toInt(a Number) *Int {
+	switch x := regulateArg(a).(type) {
+	case int32:
+		return NewInt(int64(x))
+	case *Int:
+		return x
+	}
+	panic(Errorf("integer expected: %v", a))
+}
+
+// 正確数どうしの除算で除数がゼロならば panic する。
+func checkDivisor(a, b Number) {
+	_, fa := a.(float64)
+	_, fb := b.(float64)
+	if !fa && !fb && Sign(b) == 0 {
+		panic(Errorf("division by zero: %v / %v", a, b))
+	}
+}
+
+// 剰余 (符号は被除数に従う): Remainder(7, -2) => 1
+func Remainder(a, b Number) Number {
+	checkDivisor(a, b)
+	switch x := a.(type) {
+	case int32:
+		switch y := b.(type) {
+		case int32:
+			return regulateInt64(int64(x) % int64(y))
+		}
+	}
+	return Subtract(a, Multiply(b, DivideInt(a, b)))
+}
+
+// 法 (符号は除数に従う): Modulo(7, -2) => -1
+func Modulo(a, b Number) Number {
+	r := Remainder(a, b)
+	if Sign(r) != 0 && Sign(r) != Sign(b) {
+		return Add(r, b)
+	}
+	return r
+}
+
+// 負ならば -1 を，ゼロならば 0 を，正ならば 1 を返す。Sign(-7) => -1
+func Sign(a Number) int {
+	switch x := a.(type) {
+	case int32:
+		if x < 0 {
+			return -1
+		} else if x == 0 {
+			return 0
+		}
+		return 1
+	case float64:
+		return signum(x)
+	case *Int:
+		return x.Sign()
+	case *Rat:
+		return x.Sign()
+	}
+	return Sign(regulateArg(a))
+}
+
+// 数を負の無限大の方向へ丸めた整数を返す。FloorNumber(-3.5) => -4
+func FloorNumber(a Number) Number {
+	switch x := a.(type) {
+	case int32:
+		return x
+	case float64:
+		return truncateFloat64(math.Floor(x))
+	case *Int:
+		return x
+	case *Rat:
+		// 分母は常に正だから Int の Div (ユークリッド除算) は床関数になる。
+		return regulateInt(new(Int).Div(x.Num(), x.Denom()))
+	}
+	return FloorNumber(regulateArg(a))
+}
+
+// 数を正の無限大の方向へ丸めた整数を返す。CeilingNumber(3.5) => 4
+func CeilingNumber(a Number) Number {
+	return Subtract(0, FloorNumber(Subtract(0, a)))
+}
+
+// 数を最も近い整数に丸める。ちょうど中間ならば偶数に丸める。
+// RoundNumber(2.5) => 2
+func RoundNumber(a Number) Number {
+	switch x := a.(type) {
+	case int32:
+		return x
+	case float64:
+		return truncateFloat64(math.RoundToEven(x))
+	case *Int:
+		return x
+	case *Rat:
+		fl := FloorNumber(x)
+		switch Compare(Subtract(x, fl), NewRat(1, 2)) {
+		case -1:
+			return fl
+		case 0:
+			if Sign(Remainder(fl, 2)) == 0 {
+				return fl
+			}
+		}
+		return Add(fl, 1)
+	}
+	return RoundNumber(regulateArg(a))
+}
+
+// 絶対値: Abs(-7) => 7
+func Abs(a Number) Number {
+	switch x := a.(type) {
+	case int32:
+		if x < 0 {
+			return regulateInt64(-int64(x))
+		}
+		return x
+	case float64:
+		return math.Abs(x)
+	case *Int:
+		return new(Int).Abs(x)
+	case *Rat:
+		return new(Rat).Abs(x)
+	}
+	return Abs(regulateArg(a))
+}
+
+// 小さい方の数: Min(7, 2) => 2
+func Min(a, b Number) Number {
+	if Compare(a, b) <= 0 {
+		return regulateArg(a)
+	}
+	return regulateArg(b)
+}
+
+// 大きい方の数: Max(7, 2) => 7
+func Max(a, b Number) Number {
+	if Compare(a, b) >= 0 {
+		return regulateArg(a)
+	}
+	return regulateArg(b)
+}
+
+// 整数の最大公約数 (常に非負): Gcd(12, -18) => 6
+func Gcd(a, b Number) Number {
+	switch x := a.(type) {
+	case int32:
+		switch y := b.(type) {
+		case int32:
+			m, n := int64(x), int64(y)
+			if m < 0 {
+				m = -m
+			}
+			if n < 0 {
+				n = -n
+			}
+			for n != 0 {
+				m, n = n, m%n
+			}
+			return regulateInt64(m)
+		}
+	}
+	m := new(Int).Abs(toInt(a))
+	n := new(Int).Abs(toInt(b))
+	return regulateInt(new(Int).GCD(nil, nil, m, n))
+}
+
+// 整数の最小公倍数 (常に非負): Lcm(4, -6) => 12
+func Lcm(a, b Number) Number {
+	g := Gcd(a, b)
+	if Sign(g) == 0 {
+		return int32(0)
+	}
+	return Abs(Multiply(a, DivideInt(b, g)))
+}
+
+// 累乗: Expt(2, 10) => 1024
+// 底が正確数で指数が整数ならば結果も正確数になる。
+// そうでなければ float64 で計算する。
+func Expt(a, b Number) Number {
+	if IsInteger(b) && IsRational(a) {
+		e := toInt(b)
+		if !e.IsInt64() {
+			panic(Errorf("exponent too large: %v", b))
+		}
+		x := toRat(a)
+		n, d := x.Num(), x.Denom()
+		if e.Sign() < 0 {
+			if x.Sign() == 0 {
+				panic(Errorf("division by zero: %v ^ %v", a, b))
+			}
+			n, d = d, n
+			e = new(Int).Neg(e)
+		}
+		n = new(Int).Exp(n, e, nil)
+		d = new(Int).Exp(d, e, nil)
+		return regulateRat(new(Rat).SetFrac(n, d))
+	}
+	return math.Pow(Float64(a), Float64(b))
+}
+
+// 有理数の分子: Numerator(NewRat(6, 4)) => 3
+// float64 は正確数に変換して分子を求め，float64 で返す。
+func Numerator(a Number) Number {
+	switch x := a.(type) {
+	case int32:
+		return x
+	case float64:
+		return ToInexact(Numerator(ToExact(x)))
+	case *Int:
+		return x
+	case *Rat:
+		return regulateInt(new(Int).Set(x.Num()))
+	}
+	return Numerator(regulateArg(a))
+}
+
+// 有理数の分母 (常に正): Denominator(NewRat(6, 4)) => 2
+// float64 は正確数に変換して分母を求め，float64 で返す。
+func Denominator(a Number) Number {
+	switch x := a.(type) {
+	case int32:
+		return int32(1)
+	case float64:
+		return ToInexact(Denominator(ToExact(x)))
+	case *Int:
+		return int32(1)
+	case *Rat:
+		return regulateInt(new(Int).Set(x.Denom()))
+	}
+	return Denominator(regulateArg(a))
+}
+
+// 不正確数 (float64) にする。ToInexact(NewRat(7, 2)) => 3.5
+func ToInexact(a Number) Number {
+	return Float64(a)
+}
+
+// 正確数 (int32, *Int または *Rat) にする。ToExact(3.5) => 7/2
+// 無限大や NaN は正確数にできないので panic する。
+func ToExact(a Number) Number {
+	switch x := a.(type) {
+	case float64:
+		r := new(Rat)
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			panic(Errorf("no exact representation: %v", x))
+		}
+		return regulateRat(r.SetFloat64(x))
+	}
+	return regulateArg(a)
+}
+
+// 整数ならば true を返す。float64 は整数値であっても false とする。
+func IsInteger(a interface{}) bool {
+	switch x := a.(type) {
+	case int, int32, int64, *Int:
+		return true
+	case *Rat:
+		return x.IsInt()
+	}
+	return false
+}
+
+// 正確数 (整数または有理数) ならば true を返す。
+func IsRational(a interface{}) bool {
+	switch a.(type) {
+	case int, int32, int64, *Int, *Rat:
+		return true
+	}
+	return false
+}
+
+// 浮動小数点数 (float64) ならば true を返す。
+func IsFloat(a interface{}) bool {
+	_, ok := a.(float64)
+	return ok
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,347 @@
+// H25.4/24 (鈴)
+
+package arith
+
+import (
+	. "fmt"
+	. "math/big"
+)
+
+func ExampleRemainder() {
+	r := Remainder(7, 2)
+	Printf("%T %v\n", r, r)
+	r = Remainder(-7, 2)
+	Printf("%T %v\n", r, r)
+	r = Remainder(7, -2)
+	Printf("%T %v\n", r, r)
+	r = Remainder(7.5, 2)
+	Printf("%T %v\n", r, r)
+	r = Remainder(NewRat(7, 2), 1)
+	Printf("%T %v\n", r, r)
+	r = Remainder(NewRat(7000000000001, 1), 1000000)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 1
+	// int32 -1
+	// int32 1
+	// float64 1.5
+	// *big.Rat 1/2
+	// int32 1
+}
+
+func ExampleRemainder_zero() {
+	for _, b := range []Number{0, NewInt(0), NewRat(0, 1)} {
+		func() {
+			defer func() { Println(recover()) }()
+			Remainder(5, b)
+		}()
+		func() {
+			defer func() { Println(recover()) }()
+			DivideInt(5, b)
+		}()
+	}
+	// Output:
+	// division by zero: 5 / 0
+	// division by zero: 5 / 0
+	// division by zero: 5 / 0
+	// division by zero: 5 / 0
+	// division by zero: 5 / 0/1
+	// division by zero: 5 / 0/1
+}
+
+func ExampleModulo() {
+	r := Modulo(7, 2)
+	Printf("%T %v\n", r, r)
+	r = Modulo(-7, 2)
+	Printf("%T %v\n", r, r)
+	r = Modulo(7, -2)
+	Printf("%T %v\n", r, r)
+	r = Modulo(-7, -2)
+	Printf("%T %v\n", r, r)
+	r = Modulo(-7.5, 2)
+	Printf("%T %v\n", r, r)
+	r = Modulo(NewRat(-7, 2), 1)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 1
+	// int32 1
+	// int32 -1
+	// int32 -1
+	// float64 0.5
+	// *big.Rat 1/2
+}
+
+func ExampleSign() {
+	Println(Sign(-7), Sign(0), Sign(7))
+	Println(Sign(-7.0), Sign(0.0), Sign(7.0))
+	Println(Sign(NewRat(-7, 2)), Sign(NewRat(0, 1)), Sign(NewRat(7, 2)))
+	// Output:
+	// -1 0 1
+	// -1 0 1
+	// -1 0 1
+}
+
+func ExampleFloorNumber() {
+	r := FloorNumber(7)
+	Printf("%T %v\n", r, r)
+	r = FloorNumber(3.5)
+	Printf("%T %v\n", r, r)
+	r = FloorNumber(-3.5)
+	Printf("%T %v\n", r, r)
+	r = FloorNumber(NewRat(7, 2))
+	Printf("%T %v\n", r, r)
+	r = FloorNumber(NewRat(-7, 2))
+	Printf("%T %v\n", r, r)
+	r = FloorNumber(NewRat(-7000000000001, 2))
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 7
+	// int32 3
+	// int32 -4
+	// int32 3
+	// int32 -4
+	// *big.Int -3500000000001
+}
+
+func ExampleCeilingNumber() {
+	r := CeilingNumber(7)
+	Printf("%T %v\n", r, r)
+	r = CeilingNumber(3.5)
+	Printf("%T %v\n", r, r)
+	r = CeilingNumber(-3.5)
+	Printf("%T %v\n", r, r)
+	r = CeilingNumber(NewRat(7, 2))
+	Printf("%T %v\n", r, r)
+	r = CeilingNumber(NewRat(-7, 2))
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 7
+	// int32 4
+	// int32 -3
+	// int32 4
+	// int32 -3
+}
+
+func ExampleRoundNumber() {
+	r := RoundNumber(7)
+	Printf("%T %v\n", r, r)
+	r = RoundNumber(2.5)
+	Printf("%T %v\n", r, r)
+	r = RoundNumber(3.5)
+	Printf("%T %v\n", r, r)
+	r = RoundNumber(-2.6)
+	Printf("%T %v\n", r, r)
+	r = RoundNumber(NewRat(5, 2))
+	Printf("%T %v\n", r, r)
+	r = RoundNumber(NewRat(7, 2))
+	Printf("%T %v\n", r, r)
+	r = RoundNumber(NewRat(-7, 3))
+	Printf("%T %v\n", r, r)
+	r = RoundNumber(NewRat(-5, 3))
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 7
+	// int32 2
+	// int32 4
+	// int32 -3
+	// int32 2
+	// int32 4
+	// int32 -2
+	// int32 -2
+}
+
+func ExampleAbs() {
+	r := Abs(-7)
+	Printf("%T %v\n", r, r)
+	r = Abs(-7.5)
+	Printf("%T %v\n", r, r)
+	r = Abs(NewRat(-7, 2))
+	Printf("%T %v\n", r, r)
+	r = Abs(int32(-2147483648))
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 7
+	// float64 7.5
+	// *big.Rat 7/2
+	// *big.Int 2147483648
+}
+
+func ExampleMin() {
+	r := Min(7, 2)
+	Printf("%T %v\n", r, r)
+	r = Min(7, 2.0)
+	Printf("%T %v\n", r, r)
+	r = Min(NewRat(1, 2), 1)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 2
+	// float64 2
+	// *big.Rat 1/2
+}
+
+func ExampleMax() {
+	r := Max(7, 2)
+	Printf("%T %v\n", r, r)
+	r = Max(7.0, 2)
+	Printf("%T %v\n", r, r)
+	r = Max(NewRat(1, 2), 1)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 7
+	// float64 7
+	// int32 1
+}
+
+func ExampleGcd() {
+	r := Gcd(12, 18)
+	Printf("%T %v\n", r, r)
+	r = Gcd(-12, 18)
+	Printf("%T %v\n", r, r)
+	r = Gcd(0, 0)
+	Printf("%T %v\n", r, r)
+	r = Gcd(NewRat(6000000000000, 1), 4000000000000)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 6
+	// int32 6
+	// int32 0
+	// *big.Int 2000000000000
+}
+
+func ExampleLcm() {
+	r := Lcm(4, 6)
+	Printf("%T %v\n", r, r)
+	r = Lcm(-4, 6)
+	Printf("%T %v\n", r, r)
+	r = Lcm(0, 6)
+	Printf("%T %v\n", r, r)
+	r = Lcm(4000000000, 6)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 12
+	// int32 12
+	// int32 0
+	// *big.Int 12000000000
+}
+
+func ExampleExpt() {
+	r := Expt(2, 10)
+	Printf("%T %v\n", r, r)
+	r = Expt(2, 100)
+	Printf("%T %v\n", r, r)
+	r = Expt(2, -2)
+	Printf("%T %v\n", r, r)
+	r = Expt(NewRat(2, 3), 3)
+	Printf("%T %v\n", r, r)
+	r = Expt(NewRat(-2, 3), -3)
+	Printf("%T %v\n", r, r)
+	r = Expt(7, 0)
+	Printf("%T %v\n", r, r)
+	r = Expt(2.0, 10)
+	Printf("%T %v\n", r, r)
+	r = Expt(4, NewRat(1, 2))
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 1024
+	// *big.Int 1267650600228229401496703205376
+	// *big.Rat 1/4
+	// *big.Rat 8/27
+	// *big.Rat -27/8
+	// int32 1
+	// float64 1024
+	// float64 2
+}
+
+func ExampleNumerator() {
+	r := Numerator(7)
+	Printf("%T %v\n", r, r)
+	r = Numerator(NewRat(6, -4))
+	Printf("%T %v\n", r, r)
+	r = Numerator(0.75)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 7
+	// int32 -3
+	// float64 3
+}
+
+func ExampleDenominator() {
+	r := Denominator(7)
+	Printf("%T %v\n", r, r)
+	r = Denominator(NewRat(6, -4))
+	Printf("%T %v\n", r, r)
+	r = Denominator(0.75)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 1
+	// int32 2
+	// float64 4
+}
+
+func ExampleToInexact() {
+	r := ToInexact(7)
+	Printf("%T %v\n", r, r)
+	r = ToInexact(NewRat(7, 2))
+	Printf("%T %v\n", r, r)
+	// Output:
+	// float64 7
+	// float64 3.5
+}
+
+func ExampleToExact() {
+	r := ToExact(7)
+	Printf("%T %v\n", r, r)
+	r = ToExact(3.5)
+	Printf("%T %v\n", r, r)
+	r = ToExact(0.1)
+	Printf("%T %v\n", r, r)
+	r = ToExact(1e10)
+	Printf("%T %v\n", r, r)
+	// Output:
+	// int32 7
+	// *big.Rat 7/2
+	// *big.Rat 3602879701896397/36028797018963968
+	// *big.Int 10000000000
+}
+
+func ExampleIsInteger() {
+	Println(IsInteger(7), IsInteger(7.0), IsInteger(NewRat(7, 1)),
+		IsInteger(NewRat(7, 2)), IsInteger("7"))
+	// Output:
+	// true false true false false
+}
+
+func ExampleIsRational() {
+	Println(IsRational(7), IsRational(7.0), IsRational(NewRat(7, 2)),
+		IsRational("7"))
+	// Output:
+	// true false true false
+}
+
+func ExampleIsFloat() {
+	Println(IsFloat(7), IsFloat(7.0), IsFloat(NewRat(7, 2)), IsFloat("7"))
+	// Output:
+	// false true false false
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
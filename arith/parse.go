@@ -0,0 +1,214 @@
+// H25.4/29 (鈴)
+
+// このファイルは数の文字列表現の解釈を実装する。
+// String が返す文字列はどれも Parse で元の数に戻る。
+//
+// 受け付ける文法は次のとおり (英字の大小は区別しない):
+//
+//   数       = 接頭辞* 実数
+//   接頭辞   = 基数接頭辞 | 正確性接頭辞 (それぞれ高々１個)
+//   基数接頭辞 = #x | #o | #b | #d
+//   正確性接頭辞 = #e | #i
+//   実数     = 符号? 絶対値 | +inf.0 | -inf.0 | +nan.0 | -nan.0
+//   絶対値   = 整数 | 整数 / 整数 | 10 進小数
+//   整数     = 数字+ | 0x 16進数字+ | 0o 8進数字+ | 0b 2進数字+
+//   10 進小数 = (数字+ . 数字* | . 数字+ | 数字+) 指数部?
+//              (ただし小数点も指数部もないものは整数とする)
+//   指数部   = (e | E) 符号? 数字+
+//
+// 0x, 0o, 0b は基数接頭辞がないときだけ使える。10 進小数は基数 10 に
+// 限る。10 進小数は #e がなければ float64 に，整数と分数は #i が
+// なければ正確数になる。#e 付きの 10 進小数は正確な値になる:
+// #e1.2 => 6/5
+
+package arith
+
+import (
+	. "fmt"
+	. "math/big"
+	"strings"
+)
+
+// #e 付きの 10 進小数で許す指数部の絶対値の上限
+const maxExactExponent = 10000
+
+// 数の文字列表現を解釈する。解釈できなければ error を返す。
+// Parse("-3/4") => -3/4, Parse("#x1F") => 31, Parse("#e1.5") => 3/2
+func Parse(s string) (Number, error) {
+	radix, exactness := 0, byte(0)
+	text := s
+	for len(text) >= 2 && text[0] == '#' {
+		switch c := lower(text[1]); c {
+		case 'x', 'o', 'b', 'd':
+			if radix != 0 {
+				return nil, Errorf("invalid number: %q", s)
+			}
+			radix = map[byte]int{'x': 16, 'o': 8, 'b': 2, 'd': 10}[c]
+		case 'e', 'i':
+			if exactness != 0 {
+				return nil, Errorf("invalid number: %q", s)
+			}
+			exactness = c
+		default:
+			return nil, Errorf("invalid number: %q", s)
+		}
+		text = text[2:]
+	}
+	x, ok := parseReal(text, radix, exactness == 'e')
+	if !ok {
+		return nil, Errorf("invalid number: %q", s)
+	}
+	switch exactness {
+	case 'e':
+		if IsInf(x) || IsNaN(x) {
+			return nil, Errorf("no exact representation: %q", s)
+		}
+		return ToExact(x), nil
+	case 'i':
+		return ToInexact(x), nil
+	}
+	return x, nil
+}
+
+// 英大文字ならば小文字にする。
+func lower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + ('a' - 'A')
+	}
+	return c
+}
+
+// 接頭辞を除いた実数を解釈する。radix が 0 ならば基数の指定はない。
+// exact が true ならば 10 進小数を正確数として解釈する。
+func parseReal(s string, radix int, exact bool) (Number, bool) {
+	switch strings.ToLower(s) {
+	case "+inf.0", "-inf.0", "+nan.0", "-nan.0":
+		f, err := ParseFloat64(strings.ToLower(s))
+		return f, err == nil
+	}
+	body := s
+	negative := false
+	if body != "" && (body[0] == '+' || body[0] == '-') {
+		negative = body[0] == '-'
+		body = body[1:]
+	}
+	if radix == 0 {
+		radix = 10
+		if len(body) > 2 && body[0] == '0' {
+			switch lower(body[1]) {
+			case 'x':
+				radix, body = 16, body[2:]
+			case 'o':
+				radix, body = 8, body[2:]
+			case 'b':
+				radix, body = 2, body[2:]
+			}
+		}
+	}
+	if i := strings.IndexByte(body, '/'); i >= 0 { // 分数
+		n, ok1 := parseUint(body[:i], radix)
+		d, ok2 := parseUint(body[i+1:], radix)
+		if !ok1 || !ok2 || d.Sign() == 0 {
+			return nil, false
+		}
+		if negative {
+			n.Neg(n)
+		}
+		return regulateRat(new(Rat).SetFrac(n, d)), true
+	}
+	if n, ok := parseUint(body, radix); ok { // 整数
+		if negative {
+			n.Neg(n)
+		}
+		return regulateInt(n), true
+	}
+	if radix != 10 || !isDecimal(body, exact) {
+		return nil, false
+	}
+	if exact {
+		r, ok := new(Rat).SetString(s)
+		if !ok {
+			return nil, false
+		}
+		return regulateRat(r), true
+	}
+	f, err := ParseFloat64(s)
+	return f, err == nil
+}
+
+// 基数 radix の数字の列を非負整数として解釈する。
+func parseUint(s string, radix int) (*Int, bool) {
+	if s == "" {
+		return nil, false
+	}
+	for i := 0; i < len(s); i++ {
+		if digitValue(s[i]) >= radix {
+			return nil, false
+		}
+	}
+	return new(Int).SetString(s, radix)
+}
+
+// 数字の値を返す。数字でなければ 36 を返す。
+func digitValue(c byte) int {
+	switch c = lower(c); {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'z':
+		return int(c-'a') + 10
+	}
+	return 36
+}
+
+// 符号を除いた 10 進小数の表記ならば true を返す。
+// exact が true ならば指数部の大きさを制限する。
+func isDecimal(s string, exact bool) bool {
+	mantissa, exponent := s, ""
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa, exponent = s[:i], s[i+1:]
+		if exponent != "" && (exponent[0] == '+' || exponent[0] == '-') {
+			exponent = exponent[1:]
+		}
+		e, ok := parseUint(exponent, 10)
+		if !ok || exact && e.Cmp(NewInt(maxExactExponent)) > 0 {
+			return false
+		}
+	}
+	intPart, fracPart := mantissa, ""
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		intPart, fracPart = mantissa[:i], mantissa[i+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return false
+	}
+	for _, part := range []string{intPart, fracPart} {
+		for i := 0; i < len(part); i++ {
+			if digitValue(part[i]) >= 10 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
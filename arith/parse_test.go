@@ -0,0 +1,103 @@
+// H25.4/29 (鈴)
+
+package arith
+
+import (
+	. "fmt"
+	"math"
+	. "math/big"
+)
+
+func ExampleParse() {
+	for _, s := range []string{
+		"42", "-3/4", "6/4", "0x1F", "#x1F", "#b-1010", "#o17", "1.5",
+		".5", "1e400", "#e1.5", "#e1e3", "#i1/3", "#x#e10", "+inf.0",
+		"123456789012345678901234567890",
+	} {
+		x, err := Parse(s)
+		Printf("%s => %T %s %v\n", s, x, String(x), err)
+	}
+	// Output:
+	// 42 => int32 42 <nil>
+	// -3/4 => *big.Rat -3/4 <nil>
+	// 6/4 => *big.Rat 3/2 <nil>
+	// 0x1F => int32 31 <nil>
+	// #x1F => int32 31 <nil>
+	// #b-1010 => int32 -10 <nil>
+	// #o17 => int32 15 <nil>
+	// 1.5 => float64 1.5 <nil>
+	// .5 => float64 0.5 <nil>
+	// 1e400 => float64 +inf.0 <nil>
+	// #e1.5 => *big.Rat 3/2 <nil>
+	// #e1e3 => int32 1000 <nil>
+	// #i1/3 => float64 0.3333333333333333 <nil>
+	// #x#e10 => int32 16 <nil>
+	// +inf.0 => float64 +inf.0 <nil>
+	// 123456789012345678901234567890 => *big.Int 123456789012345678901234567890 <nil>
+}
+
+func ExampleParse_error() {
+	for _, s := range []string{
+		"", "-", "1/0", "1/-2", "#x1.5", "#x#o1", "#e+inf.0", "1e", "0x",
+		"1_000", "inf", "1.5/2",
+	} {
+		_, err := Parse(s)
+		Println(err)
+	}
+	// Output:
+	// invalid number: ""
+	// invalid number: "-"
+	// invalid number: "1/0"
+	// invalid number: "1/-2"
+	// invalid number: "#x1.5"
+	// invalid number: "#x#o1"
+	// no exact representation: "#e+inf.0"
+	// invalid number: "1e"
+	// invalid number: "0x"
+	// invalid number: "1_000"
+	// invalid number: "inf"
+	// invalid number: "1.5/2"
+}
+
+func ExampleParse_roundTrip() {
+	for _, a := range []Number{
+		int32(-7), NewRat(-1, 3), 0.1, math.Copysign(0, -1), 1e21,
+		math.MaxFloat64, math.SmallestNonzeroFloat64, math.Inf(-1),
+		new(Int).Lsh(NewInt(1), 100),
+	} {
+		b, err := Parse(String(a))
+		Println(String(b), err == nil && Compare(a, b) == 0)
+	}
+	// Output:
+	// -7 true
+	// -1/3 true
+	// 0.1 true
+	// -0.0 true
+	// 1e+21 true
+	// 1.7976931348623157e+308 true
+	// 5e-324 true
+	// -inf.0 true
+	// 1267650600228229401496703205376 true
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,113 @@
+// H25.5/1 (鈴)
+
+// このファイルは擬似乱数を実装する。
+// 種を指定した RandomState は同じ乱数列を再現する。
+// RandomState を使わない Random は，ゴルーチンどうしがロックで競合
+// しないよう，sync.Pool に置いた生成器を (実質的に P ごとに) 使う。
+
+package arith
+
+import (
+	. "fmt"
+	. "math/big"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 擬似乱数の状態
+type RandomState struct {
+	rng  *rand.Rand
+	lock sync.Mutex
+}
+
+// 種 seed に対する擬似乱数の状態を作る。
+func NewRandomState(seed int64) *RandomState {
+	return &RandomState{rng: rand.New(rand.NewSource(seed))}
+}
+
+func (rs *RandomState) String() string {
+	return "#<random-state>"
+}
+
+// 0 以上 limit 未満の一様な擬似乱数を返す。
+// limit は正の整数 (結果は整数) または正の float64 (結果は float64)
+// とする。RandomState は複数のゴルーチンから使ってもよい。
+func (rs *RandomState) Random(limit Number) Number {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	return random(rs.rng, limit)
+}
+
+var seedCounter int64
+
+// 種を時刻から作る生成器のプール
+var randomPool = sync.Pool{
+	New: func() interface{} {
+		seed := time.Now().UnixNano() + atomic.AddInt64(&seedCounter, 1)
+		return rand.New(rand.NewSource(seed))
+	},
+}
+
+// 0 以上 limit 未満の一様な擬似乱数を返す。
+// Random(6) は 0 から 5 までの int32 を，Random(1.0) は [0, 1) の
+// float64 を返す。種は時刻から作る。
+func Random(limit Number) Number {
+	rng := randomPool.Get().(*rand.Rand)
+	defer randomPool.Put(rng)
+	return random(rng, limit)
+}
+
+func random(rng *rand.Rand, limit Number) Number {
+	if Sign(limit) <= 0 {
+		panic(Errorf("positive number expected: %v", limit))
+	}
+	switch x := regulateArg(limit).(type) {
+	case int32:
+		return int32(rng.Int63n(int64(x)))
+	case float64:
+		return rng.Float64() * x
+	case *Int:
+		return regulateInt(randomInt(rng, x))
+	}
+	panic(Errorf("integer or float expected: %v", limit))
+}
+
+// 0 以上 n 未満の多倍長整数の擬似乱数を棄却法で作る。
+func randomInt(rng *rand.Rand, n *Int) *Int {
+	k := n.BitLen()
+	buf := make([]byte, (k+7)/8)
+	x := new(Int)
+	for {
+		rng.Read(buf)
+		if k%8 != 0 {
+			buf[0] &= byte(1)<<uint(k%8) - 1
+		}
+		if x.SetBytes(buf).Cmp(n) < 0 {
+			return x
+		}
+	}
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
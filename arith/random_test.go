@@ -0,0 +1,68 @@
+// H25.5/1 (鈴)
+
+package arith
+
+import (
+	. "fmt"
+	. "math/big"
+)
+
+func ExampleRandomState_Random() {
+	a := NewRandomState(42)
+	b := NewRandomState(42)
+	for i := 0; i < 3; i++ {
+		x, y := a.Random(1000), b.Random(1000)
+		Printf("%T %v\n", x, Compare(x, y) == 0)
+	}
+	n := new(Int).Lsh(NewInt(1), 100)
+	x, y := a.Random(n), b.Random(n)
+	Printf("%T %v\n", x, Compare(x, y) == 0)
+	x = a.Random(1.0)
+	Printf("%T %v\n", x, 0 <= x.(float64) && x.(float64) < 1)
+	// Output:
+	// int32 true
+	// int32 true
+	// int32 true
+	// *big.Int true
+	// float64 true
+}
+
+func ExampleRandom() {
+	counts := make([]int, 6)
+	for i := 0; i < 6000; i++ {
+		counts[Random(6).(int32)]++
+	}
+	ok := true
+	for _, c := range counts {
+		ok = ok && 800 < c && c < 1200
+	}
+	Println(ok)
+	n := new(Int).Lsh(NewInt(1), 64)
+	r := Random(n)
+	Println(Sign(r) >= 0 && Compare(r, n) < 0)
+	// Output:
+	// true
+	// true
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
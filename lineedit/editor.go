@@ -0,0 +1,513 @@
+// H25.5/11 (鈴)
+
+// このパッケージは端末で入力を編集する行エディタを実装する。
+// cgo を使わず termios を直接操作する。Emacs 風のキー操作と，履歴と，
+// 閉じ括弧に対応する開き括弧の強調表示と，複数行の編集と，Tab による
+// 補完ができる。入力が端末でなければ単に一行ずつ読む。
+//
+//   Ctrl-A, Home  行頭へ            Ctrl-E, End    行末へ
+//   Ctrl-B, ←     一文字戻る        Ctrl-F, →      一文字進む
+//   Alt-B         一語戻る          Alt-F          一語進む
+//   Ctrl-P, ↑     前の行か前の履歴  Ctrl-N, ↓      次の行か次の履歴
+//   Ctrl-H, BS    前の文字を消す    Ctrl-D, Del    カーソルの文字を消す
+//   Ctrl-K        行末まで消す      Ctrl-U         行頭まで消す
+//   Ctrl-W        前の一語を消す    Ctrl-Y         消した文字列を戻す
+//   Ctrl-J, Alt-Enter 改行を挿入    Tab            補完
+//   Ctrl-L        画面を消す        Ctrl-C         入力を取り消す
+//
+// Enter は NeedsMore が真ならば改行を挿入し，さもなくば入力を終える。
+// 空の入力での Ctrl-D は io.EOF を返す。
+
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Ctrl-C で入力を取り消したときに ReadLine が返すエラー
+var ErrInterrupted = errors.New("interrupted")
+
+// 行エディタ
+type Editor struct {
+	History            *History
+	Complete           func(prefix string) []string // prefix で始まる補完の候補
+	NeedsMore          func(text string) bool       // 入力が続くならば true
+	ContinuationPrompt string                       // 二行目以降のプロンプト
+	in                 *os.File
+	out                io.Writer
+	rd                 *bufio.Reader
+}
+
+// in から読み out に表示する行エディタを作る。
+func New(in *os.File, out io.Writer) *Editor {
+	return NewWithReader(in, bufio.NewReader(in), out)
+}
+
+// in の入力をバッファ付きの rd から読み out に表示する行エディタを作る。
+// rd を in の他の読み手と共有すれば，先に入力された文字を互いに失わない。
+func NewWithReader(in *os.File, rd *bufio.Reader, out io.Writer) *Editor {
+	return &Editor{History: NewHistory(1000), ContinuationPrompt: "  ",
+		in: in, out: out, rd: rd}
+}
+
+// プロンプトを表示して入力を読む。入力の最後の改行は含めない。
+// 端末ならば入力を編集でき，入力は履歴に加えられる。
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	fd := int(e.in.Fd())
+	if !IsTerminal(fd) {
+		return e.readPlain(prompt)
+	}
+	restore, err := makeRaw(fd)
+	if err != nil {
+		return e.readPlain(prompt)
+	}
+	defer restore()
+	s := &state{e: e, prompt: prompt, hist: e.History.Len(),
+		width: func() int { return terminalWidth(fd) },
+		w:     bufio.NewWriter(e.out)}
+	line, err := s.edit()
+	if err == nil {
+		e.History.Add(line) // 履歴のファイルに書けなくても入力は返す
+	}
+	return line, err
+}
+
+// 端末でない入力から一行を読む。
+func (e *Editor) readPlain(prompt string) (string, error) {
+	io.WriteString(e.out, prompt)
+	line, err := e.rd.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// 一回の入力の編集の状態
+type state struct {
+	e         *Editor
+	prompt    string
+	width     func() int    // 端末の桁数
+	w         *bufio.Writer // 端末への出力
+	buf       []rune        // 入力中の文字列
+	pos       int           // カーソルの位置 (buf の添字)
+	hist      int           // 表示中の履歴の番号. History.Len() ならば新しい入力
+	saved     []rune        // 履歴を表示する間の新しい入力
+	killed    []rune        // Ctrl-K などで消した文字列
+	cursorRow int           // 前回の表示のカーソルの行 (最初の行を 0 とする)
+	endRow    int           // 前回の表示の最後の行
+}
+
+// キー入力を処理して，入力を終えたらその文字列を返す。
+func (s *state) edit() (string, error) {
+	s.refresh()
+	for {
+		r, _, err := s.e.rd.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r': // Enter
+			text := string(s.buf)
+			if s.e.NeedsMore != nil && s.e.NeedsMore(text) {
+				s.insert([]rune{'\n'})
+				break
+			}
+			s.finish("")
+			return text, nil
+		case '\n': // Ctrl-J
+			s.insert([]rune{'\n'})
+		case 1: // Ctrl-A
+			s.pos = s.lineStart(s.pos)
+		case 2: // Ctrl-B
+			s.moveTo(s.pos - 1)
+		case 3: // Ctrl-C
+			s.finish("^C")
+			return "", ErrInterrupted
+		case 4: // Ctrl-D
+			if len(s.buf) == 0 {
+				s.finish("")
+				return "", io.EOF
+			}
+			s.delete(s.pos, s.pos+1)
+		case 5: // Ctrl-E
+			s.pos = s.lineEnd(s.pos)
+		case 6: // Ctrl-F
+			s.moveTo(s.pos + 1)
+		case 8, 127: // Ctrl-H, BS
+			s.delete(s.pos-1, s.pos)
+		case '\t':
+			s.complete()
+		case 11: // Ctrl-K
+			s.kill(s.pos, s.lineEnd(s.pos))
+		case 12: // Ctrl-L
+			s.w.WriteString("\x1b[H\x1b[2J")
+			s.cursorRow = 0
+		case 14: // Ctrl-N
+			s.down()
+		case 16: // Ctrl-P
+			s.up()
+		case 21: // Ctrl-U
+			s.kill(s.lineStart(s.pos), s.pos)
+		case 23: // Ctrl-W
+			s.kill(s.wordLeft(), s.pos)
+		case 25: // Ctrl-Y
+			s.insert(s.killed)
+		case 27: // ESC
+			s.escape()
+		default:
+			if r >= ' ' {
+				s.insert([]rune{r})
+			}
+		}
+		s.refresh()
+	}
+}
+
+// ESC で始まるキー入力を処理する。
+func (s *state) escape() {
+	r, _, err := s.e.rd.ReadRune()
+	if err != nil {
+		return
+	}
+	switch r {
+	case '[', 'O':
+		var param []rune
+		for {
+			r, _, err = s.e.rd.ReadRune()
+			if err != nil {
+				return
+			}
+			if 0x40 <= r && r <= 0x7e {
+				break
+			}
+			param = append(param, r)
+		}
+		switch r {
+		case 'A':
+			s.up()
+		case 'B':
+			s.down()
+		case 'C':
+			s.moveTo(s.pos + 1)
+		case 'D':
+			s.moveTo(s.pos - 1)
+		case 'H':
+			s.pos = s.lineStart(s.pos)
+		case 'F':
+			s.pos = s.lineEnd(s.pos)
+		case '~':
+			switch string(param) {
+			case "1", "7":
+				s.pos = s.lineStart(s.pos)
+			case "4", "8":
+				s.pos = s.lineEnd(s.pos)
+			case "3":
+				s.delete(s.pos, s.pos+1)
+			}
+		}
+	case 'b', 'B':
+		s.pos = s.wordLeft()
+	case 'f', 'F':
+		s.pos = s.wordRight()
+	case '\r':
+		s.insert([]rune{'\n'})
+	}
+}
+
+func (s *state) moveTo(pos int) {
+	if 0 <= pos && pos <= len(s.buf) {
+		s.pos = pos
+	} else {
+		s.beep()
+	}
+}
+
+// カーソルの位置に挿入する。
+func (s *state) insert(text []rune) {
+	buf := make([]rune, 0, len(s.buf)+len(text))
+	buf = append(buf, s.buf[:s.pos]...)
+	buf = append(buf, text...)
+	s.buf = append(buf, s.buf[s.pos:]...)
+	s.pos += len(text)
+}
+
+// buf[from:to] を消す。
+func (s *state) delete(from, to int) {
+	if from < 0 || to > len(s.buf) || from >= to {
+		s.beep()
+		return
+	}
+	s.buf = append(s.buf[:from], s.buf[to:]...)
+	s.pos = from
+}
+
+// buf[from:to] を消して Ctrl-Y で戻せるように覚える。
+func (s *state) kill(from, to int) {
+	if from < to {
+		s.killed = append([]rune(nil), s.buf[from:to]...)
+	}
+	s.delete(from, to)
+}
+
+// pos を含む行の先頭の位置を返す。
+func (s *state) lineStart(pos int) int {
+	for pos > 0 && s.buf[pos-1] != '\n' {
+		pos--
+	}
+	return pos
+}
+
+// pos を含む行の末尾 (改行の位置か buf の長さ) を返す。
+func (s *state) lineEnd(pos int) int {
+	for pos < len(s.buf) && s.buf[pos] != '\n' {
+		pos++
+	}
+	return pos
+}
+
+// 前の語の先頭の位置を返す。
+func (s *state) wordLeft() int {
+	pos := s.pos
+	for pos > 0 && isDelimiter(s.buf[pos-1]) {
+		pos--
+	}
+	for pos > 0 && !isDelimiter(s.buf[pos-1]) {
+		pos--
+	}
+	return pos
+}
+
+// 次の語の末尾の位置を返す。
+func (s *state) wordRight() int {
+	pos := s.pos
+	for pos < len(s.buf) && isDelimiter(s.buf[pos]) {
+		pos++
+	}
+	for pos < len(s.buf) && !isDelimiter(s.buf[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// 前の行へ移る。最初の行ならば前の履歴を表示する。
+func (s *state) up() {
+	start := s.lineStart(s.pos)
+	if start == 0 {
+		s.showHistory(s.hist - 1)
+		return
+	}
+	prev := s.lineStart(start - 1)
+	s.pos = min(prev+s.pos-start, start-1)
+}
+
+// 次の行へ移る。最後の行ならば次の履歴を表示する。
+func (s *state) down() {
+	end := s.lineEnd(s.pos)
+	if end == len(s.buf) {
+		s.showHistory(s.hist + 1)
+		return
+	}
+	next := end + 1
+	s.pos = min(next+s.pos-s.lineStart(s.pos), s.lineEnd(next))
+}
+
+// i 番目の履歴を表示する。History.Len() 番目は新しい入力とする。
+func (s *state) showHistory(i int) {
+	h := s.e.History
+	if i < 0 || i > h.Len() {
+		s.beep()
+		return
+	}
+	if s.hist == h.Len() {
+		s.saved = s.buf
+	}
+	s.hist = i
+	if i == h.Len() {
+		s.buf = s.saved
+	} else {
+		s.buf = []rune(h.At(i))
+	}
+	s.pos = len(s.buf)
+}
+
+// カーソルの前の語を補完する。候補が複数あれば共通の接頭辞まで補い，
+// それ以上補えなければ候補を一覧する。語が空ならば空白を二つ挿入する。
+func (s *state) complete() {
+	start := wordStart(s.buf, s.pos)
+	prefix := string(s.buf[start:s.pos])
+	if s.e.Complete == nil || prefix == "" {
+		s.insert([]rune("  "))
+		return
+	}
+	var cands []string
+	for _, c := range s.e.Complete(prefix) {
+		if strings.HasPrefix(c, prefix) {
+			cands = append(cands, c)
+		}
+	}
+	if len(cands) == 0 {
+		s.beep()
+		return
+	}
+	if common := commonPrefix(cands); len(common) > len(prefix) {
+		s.insert([]rune(common[len(prefix):]))
+		return
+	}
+	if len(cands) > 1 {
+		s.list(cands)
+	}
+}
+
+// 入力の下に候補を一覧する。
+func (s *state) list(cands []string) {
+	if n := s.endRow - s.cursorRow; n > 0 {
+		fmt.Fprintf(s.w, "\x1b[%dB", n)
+	}
+	s.w.WriteString("\r\n")
+	colWidth := 0
+	for _, c := range cands {
+		colWidth = max(colWidth, stringWidth(c)+2)
+	}
+	ncols := max(1, s.width()/colWidth)
+	for i, c := range cands {
+		s.w.WriteString(c)
+		if i%ncols == ncols-1 || i == len(cands)-1 {
+			s.w.WriteString("\r\n")
+		} else {
+			s.w.WriteString(strings.Repeat(" ", colWidth-stringWidth(c)))
+		}
+	}
+	s.cursorRow = 0
+}
+
+// 候補に共通の接頭辞を返す。
+func commonPrefix(cands []string) string {
+	p := cands[0]
+	for _, c := range cands[1:] {
+		for !strings.HasPrefix(c, p) {
+			_, size := lastRune(p)
+			p = p[:len(p)-size]
+		}
+	}
+	return p
+}
+
+func lastRune(s string) (rune, int) {
+	r := []rune(s)
+	last := r[len(r)-1]
+	return last, len(string(last))
+}
+
+func (s *state) beep() {
+	s.w.WriteString("\a")
+}
+
+// 入力を表示し直してカーソルを置く。
+func (s *state) refresh() {
+	s.render(true)
+	s.w.Flush()
+}
+
+// カーソルを入力の最後に置いて表示し，mark を書いて改行する。
+func (s *state) finish(mark string) {
+	s.pos = len(s.buf)
+	s.render(false)
+	s.w.WriteString(mark + "\r\n")
+	s.w.Flush()
+}
+
+// 入力を表示する。折り返しは端末に任せず自分で改行する。
+// showMatch が真でカーソルの直前が閉じ括弧ならば，対応する開き括弧を
+// 反転表示する。
+func (s *state) render(showMatch bool) {
+	width := s.width()
+	match := -1
+	if showMatch && s.pos > 0 {
+		match = MatchingParen(s.buf, s.pos-1)
+	}
+	if s.cursorRow > 0 {
+		fmt.Fprintf(s.w, "\x1b[%dA", s.cursorRow)
+	}
+	s.w.WriteString("\r\x1b[J")
+	row, col := 0, 0
+	put := func(r rune, highlight bool) {
+		rw := runeWidth(r)
+		if col+rw > width {
+			s.w.WriteString("\r\n")
+			row, col = row+1, 0
+		}
+		if highlight {
+			s.w.WriteString("\x1b[7m" + string(r) + "\x1b[0m")
+		} else {
+			s.w.WriteRune(r)
+		}
+		col += rw
+	}
+	wrap := func() { // 行末にちょうど達していれば次の行へ
+		if col >= width {
+			s.w.WriteString("\r\n")
+			row, col = row+1, 0
+		}
+	}
+	for _, r := range s.prompt {
+		put(r, false)
+	}
+	curRow, curCol := 0, 0
+	for i, r := range s.buf {
+		if i == s.pos {
+			wrap()
+			curRow, curCol = row, col
+		}
+		if r == '\n' {
+			s.w.WriteString("\r\n")
+			row, col = row+1, 0
+			for _, r := range s.e.ContinuationPrompt {
+				put(r, false)
+			}
+		} else {
+			put(r, i == match)
+		}
+	}
+	wrap()
+	if s.pos == len(s.buf) {
+		curRow, curCol = row, col
+	}
+	if n := row - curRow; n > 0 {
+		fmt.Fprintf(s.w, "\x1b[%dA", n)
+	}
+	s.w.WriteString("\r")
+	if curCol > 0 {
+		fmt.Fprintf(s.w, "\x1b[%dC", curCol)
+	}
+	s.cursorRow, s.endRow = curRow, row
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
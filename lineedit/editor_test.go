@@ -0,0 +1,76 @@
+// H25.5/11 (鈴)
+
+package lineedit
+
+import (
+	"bufio"
+	. "fmt"
+	"io"
+	"strings"
+)
+
+// キー入力の列 keys を編集して得た入力を返す。
+func editKeys(keys string, h *History) (string, error) {
+	e := &Editor{History: h, NeedsMore: Incomplete, ContinuationPrompt: "  ",
+		Complete: func(prefix string) []string {
+			return []string{"write", "write-string", "with-open-file"}
+		},
+		out: io.Discard, rd: bufio.NewReader(strings.NewReader(keys))}
+	s := &state{e: e, prompt: "> ", hist: h.Len(),
+		width: func() int { return 20 }, w: bufio.NewWriter(io.Discard)}
+	return s.edit()
+}
+
+func ExampleEditor_keys() {
+	h := NewHistory(10)
+	h.Add("(old 1)")
+	h.Add("(old 2)")
+	for _, keys := range []string{
+		"abc\x02\x02X\r",                   // Ctrl-B で戻って挿入
+		"hello world\x17\x19\x19\r",        // Ctrl-W で消して Ctrl-Y で二度戻す
+		"(list 1\r2)\r",                    // 括弧が閉じるまで Enter は改行
+		"(a\r(b\x1b[A\x05 c\x1b[B\x05))\r", // ↑ と ↓ で行を移る
+		"\x10\x10\x0e\r",                   // Ctrl-P と Ctrl-N で履歴をたどる
+		"wri\t-s\t\r",                      // 共通の接頭辞まで補完する
+		"x\x1b[D\x1b[3~y\x01z\r",           // ←, Delete, Ctrl-A
+		"abc\x03",                          // Ctrl-C で取り消す
+		"\x04",                             // 空の入力での Ctrl-D は EOF
+		"日本語\x02\x08\r",                    // 多バイト文字
+	} {
+		s, err := editKeys(keys, h)
+		Printf("%q %v\n", s, err)
+	}
+	// Output:
+	// "aXbc" <nil>
+	// "hello worldworld" <nil>
+	// "(list 1\n2)" <nil>
+	// "(a c\n(b))" <nil>
+	// "(old 2)" <nil>
+	// "write-string" <nil>
+	// "zy" <nil>
+	// "" interrupted
+	// "" EOF
+	// "日語" <nil>
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
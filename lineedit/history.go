@@ -0,0 +1,146 @@
+// H25.5/11 (鈴)
+
+// このファイルは入力の履歴を実装する。
+// 履歴のファイルには一項目を一行で書く。項目の中の改行は \n と，
+// \ は \\ と書く。
+
+package lineedit
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// 入力の履歴
+type History struct {
+	Max     int      // 覚えておく項目の最大数
+	File    string   // 空でなければ Add のたびに追記するファイル
+	entries []string // 古い順の項目
+}
+
+// 最大 max 項目を覚える履歴を作る。
+func NewHistory(max int) *History {
+	return &History{Max: max}
+}
+
+// 項目の数を返す。
+func (h *History) Len() int {
+	return len(h.entries)
+}
+
+// i 番目 (0 が最も古い) の項目を返す。
+func (h *History) At(i int) string {
+	return h.entries[i]
+}
+
+// 項目を加える。空白だけの行と直前と同じ行は加えない。
+// File が空でなければそのファイルにも追記する。
+func (h *History) Add(line string) error {
+	if strings.TrimSpace(line) == "" ||
+		len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+		return nil
+	}
+	h.entries = append(h.entries, line)
+	if h.Max > 0 && len(h.entries) > h.Max {
+		h.entries = h.entries[len(h.entries)-h.Max:]
+	}
+	if h.File == "" {
+		return nil
+	}
+	file, err := os.OpenFile(h.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(encodeEntry(line) + "\n")
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ファイルから履歴を読み，以後の Add の追記先とする。ファイルが無ければ
+// 何もしない。ファイルが Max の 2 倍より長ければ最近の Max 項目に縮める。
+func (h *History) Load(name string) error {
+	h.File = name
+	file, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var lines []string
+	sc := bufio.NewScanner(file)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		lines = append(lines, decodeEntry(sc.Text()))
+	}
+	file.Close()
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	h.entries = append(h.entries, lines...)
+	if h.Max > 0 && len(h.entries) > h.Max {
+		h.entries = h.entries[len(h.entries)-h.Max:]
+	}
+	if h.Max > 0 && len(lines) > 2*h.Max {
+		return h.Save(name)
+	}
+	return nil
+}
+
+// 履歴全体をファイルに書く。
+func (h *History) Save(name string) error {
+	var b strings.Builder
+	for _, e := range h.entries {
+		b.WriteString(encodeEntry(e))
+		b.WriteByte('\n')
+	}
+	return os.WriteFile(name, []byte(b.String()), 0600)
+}
+
+var entryEncoder = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func encodeEntry(s string) string {
+	return entryEncoder.Replace(s)
+}
+
+func decodeEntry(s string) string {
+	if !strings.ContainsRune(s, '\\') {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == 'n' {
+				b.WriteByte('\n')
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,53 @@
+// H25.5/11 (鈴)
+
+package lineedit
+
+import (
+	. "fmt"
+	"os"
+	"path/filepath"
+)
+
+func ExampleHistory() {
+	dir, _ := os.MkdirTemp("", "lineedit")
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "history")
+	h := NewHistory(3)
+	h.Load(name)
+	for _, s := range []string{"a", "a", " ", "(list 1\n  2)", `"\n"`, "b"} {
+		h.Add(s)
+	}
+	for i := 0; i < h.Len(); i++ {
+		Printf("%q\n", h.At(i))
+	}
+	h2 := NewHistory(10)
+	h2.Load(name)
+	Println(h2.Len(), h2.At(1) == h.At(0))
+	// Output:
+	// "(list 1\n  2)"
+	// "\"\\n\""
+	// "b"
+	// 4 true
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
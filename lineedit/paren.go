@@ -0,0 +1,124 @@
+// H25.5/11 (鈴)
+
+// このファイルは Lisp の式の括弧の対応を調べる。
+// 文字列 "..." の中 (\ によるエスケープを含む) と ; から行末までの
+// コメントの中の括弧は数えない。
+
+package lineedit
+
+// 式を読むと閉じていない括弧の数と，文字列の途中で終わっているかを返す。
+func Depth(text string) (depth int, inString bool) {
+	scanParens([]rune(text), func(i int, r rune) {
+		if r == '(' {
+			depth++
+		} else {
+			depth--
+		}
+	}, &inString)
+	return
+}
+
+// 閉じていない括弧か文字列があれば true を返す。
+// Editor.NeedsMore に使う。
+func Incomplete(text string) bool {
+	depth, inString := Depth(text)
+	return depth > 0 || inString
+}
+
+// text[pos] の閉じ括弧に対応する開き括弧の位置を返す。無ければ -1 を返す。
+func MatchingParen(text []rune, pos int) int {
+	if pos < 0 || pos >= len(text) || text[pos] != ')' {
+		return -1
+	}
+	var stack []int
+	match := -1
+	var inString bool
+	scanParens(text[:pos+1], func(i int, r rune) {
+		if r == '(' {
+			stack = append(stack, i)
+		} else if len(stack) > 0 {
+			if i == pos {
+				match = stack[len(stack)-1]
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}, &inString)
+	return match
+}
+
+// 文字列とコメントの外の括弧ごとに f を呼ぶ。
+// 最後に文字列の途中ならば *inString を true にする。
+func scanParens(text []rune, f func(i int, r rune), inString *bool) {
+	const (
+		code = iota
+		str
+		escape
+		comment
+	)
+	mode := code
+	for i, r := range text {
+		switch mode {
+		case code:
+			switch r {
+			case '(', ')':
+				f(i, r)
+			case '"':
+				mode = str
+			case ';':
+				mode = comment
+			}
+		case str:
+			if r == '\\' {
+				mode = escape
+			} else if r == '"' {
+				mode = code
+			}
+		case escape:
+			mode = str
+		case comment:
+			if r == '\n' {
+				mode = code
+			}
+		}
+	}
+	*inString = mode == str || mode == escape
+}
+
+// 補完の対象とする単語 (シンボル) の先頭の位置を返す。
+func wordStart(text []rune, pos int) int {
+	for pos > 0 && !isDelimiter(text[pos-1]) {
+		pos--
+	}
+	return pos
+}
+
+// 単語の区切りとなる文字ならば true を返す。
+func isDelimiter(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '(', ')', '\'', '"', ';', ',':
+		return true
+	}
+	return false
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,57 @@
+// H25.5/11 (鈴)
+
+package lineedit
+
+import (
+	. "fmt"
+)
+
+func ExampleDepth() {
+	for _, s := range []string{
+		"(+ 1 2)", "(list 1", `(print "(")`, `(print "a\"`, "(a ; )\n", "())",
+	} {
+		d, inString := Depth(s)
+		Println(d, inString, Incomplete(s))
+	}
+	// Output:
+	// 0 false false
+	// 1 false true
+	// 0 false false
+	// 1 true true
+	// 1 false true
+	// -1 false false
+}
+
+func ExampleMatchingParen() {
+	text := []rune(`(a (b ")") c)`)
+	for _, i := range []int{7, 9, 12, 0} {
+		Println(i, MatchingParen(text, i))
+	}
+	// Output:
+	// 7 -1
+	// 9 3
+	// 12 0
+	// 0 -1
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
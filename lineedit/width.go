@@ -0,0 +1,59 @@
+// H25.5/11 (鈴)
+
+package lineedit
+
+import "unicode"
+
+// 端末での文字の表示幅を返す。東アジアの全角文字は 2 とする。
+func runeWidth(r rune) int {
+	switch {
+	case r < ' ' || r == 0x7f:
+		return 0
+	case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r):
+		return 0
+	case 0x1100 <= r && r <= 0x115f, // ハングル字母
+		0x2e80 <= r && r <= 0x303e, // CJK 部首など
+		0x3041 <= r && r <= 0xa4cf, // 仮名, CJK 統合漢字など
+		0xac00 <= r && r <= 0xd7a3, // ハングル音節
+		0xf900 <= r && r <= 0xfaff, // CJK 互換漢字
+		0xfe30 <= r && r <= 0xfe4f, // CJK 互換形
+		0xff00 <= r && r <= 0xff60, // 全角形
+		0xffe0 <= r && r <= 0xffe6,
+		0x1f300 <= r && r <= 0x1f64f, // 絵文字
+		0x1f900 <= r && r <= 0x1f9ff,
+		0x20000 <= r && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
+
+// 文字列の表示幅を返す。
+func stringWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,264 @@
+// H25.4/22 (鈴)
+
+// このファイルは lambda と defun の仮引数リストを実装する。
+
+package lisp
+
+import (
+	"fmt"
+)
+
+// 仮引数リスト (lambda list) を解析した結果.
+// (必須引数... [&optional 任意引数...] [&rest 変数] [&key キー引数...
+// [&allow-other-keys]]) という形をとる。
+type LambdaList struct {
+	Source         *Cell // 元の仮引数リスト (エラー表示用)
+	Required       []*Symbol
+	Optional       []OptionalParam
+	Rest           *Symbol
+	HasKey         bool
+	Keys           []KeyParam
+	AllowOtherKeys bool
+}
+
+// &optional の仮引数. var, (var default) または (var default supplied-p)
+type OptionalParam struct {
+	Var      *Symbol
+	Default  Any
+	Supplied *Symbol // 実引数が与えられたかどうかを束縛する変数 (無ければ nil)
+}
+
+// &key の仮引数. &optional の形に加え ((:keyword var) default supplied-p)
+// という形でキーワードを明示できる。
+type KeyParam struct {
+	Keyword *Symbol
+	OptionalParam
+}
+
+// 仮引数リストを解析する。
+func ParseLambdaList(params *Cell) *LambdaList {
+	ll := &LambdaList{Source: params}
+	state := (*Symbol)(nil) // 直前の &… (必須引数ならば nil)
+	for x := params; x != nil; x = x.Cdr {
+		switch x.Car {
+		case AmpOptionalSymbol:
+			if state != nil {
+				ll.badParam(x.Car)
+			}
+			state = AmpOptionalSymbol
+			continue
+		case AmpRestSymbol:
+			if state == AmpRestSymbol || state == AmpKeySymbol ||
+				x.Cdr == nil {
+				ll.badParam(x.Car)
+			}
+			state = AmpRestSymbol
+			x = x.Cdr
+			ll.Rest = ll.checkVar(x.Car)
+			continue
+		case AmpKeySymbol:
+			if state == AmpKeySymbol {
+				ll.badParam(x.Car)
+			}
+			state = AmpKeySymbol
+			ll.HasKey = true
+			continue
+		case AmpAllowOtherKeysSymbol:
+			if state != AmpKeySymbol || x.Cdr != nil {
+				ll.badParam(x.Car)
+			}
+			ll.AllowOtherKeys = true
+			continue
+		}
+		switch state {
+		case nil:
+			ll.Required = append(ll.Required, ll.checkVar(x.Car))
+		case AmpOptionalSymbol:
+			ll.Optional = append(ll.Optional, ll.parseOptional(x.Car))
+		case AmpKeySymbol:
+			ll.Keys = append(ll.Keys, ll.parseKey(x.Car))
+		default: // &rest の変数の後に余分なものがある。
+			ll.badParam(x.Car)
+		}
+	}
+	return ll
+}
+
+func (ll *LambdaList) badParam(a Any) {
+	panic(fmt.Errorf("bad parameter %s in lambda list %s",
+		StringFor(a), StringFor(ll.Source)))
+}
+
+func (ll *LambdaList) checkVar(a Any) *Symbol {
+	if sym, ok := a.(*Symbol); ok && !sym.IsKeyword() &&
+		sym != TSymbol && sym != NilSymbol {
+		return sym
+	}
+	ll.badParam(a)
+	return nil
+}
+
+func (ll *LambdaList) parseOptional(a Any) OptionalParam {
+	switch x := a.(type) {
+	case *Symbol:
+		return OptionalParam{ll.checkVar(x), (*Cell)(nil), nil}
+	case *Cell:
+		if x != nil {
+			op := OptionalParam{ll.checkVar(x.Car), (*Cell)(nil), nil}
+			y := x.Cdr
+			if y == nil {
+				return op
+			}
+			op.Default = y.Car
+			z := y.Cdr
+			if z == nil {
+				return op
+			}
+			op.Supplied = ll.checkVar(z.Car)
+			if z.Cdr == nil {
+				return op
+			}
+		}
+	}
+	ll.badParam(a)
+	return OptionalParam{}
+}
+
+func (ll *LambdaList) parseKey(a Any) KeyParam {
+	if x, ok := a.(*Cell); ok && x != nil {
+		if y, ok := x.Car.(*Cell); ok { // ((:keyword var) ...)
+			kw, v := CheckForBinary(y)
+			key, ok := kw.(*Symbol)
+			if !ok || !key.IsKeyword() {
+				ll.badParam(a)
+			}
+			op := ll.parseOptional(Cons(v, x.Cdr))
+			return KeyParam{key, op}
+		}
+	}
+	op := ll.parseOptional(a)
+	return KeyParam{Keyword(op.Var.string), op}
+}
+
+// 実引数の式の並び args を環境 argsEnv で評価して新しい環境 env に
+// 束縛する。必須の仮引数だけで数が合えば，評価した値を直接束縛する。
+func (ll *LambdaList) BindArgs(args *Cell, argsEnv, env *Env) {
+	if ll.Optional == nil && ll.Rest == nil && !ll.HasKey &&
+		listLength(args) == len(ll.Required) {
+		for _, sym := range ll.Required { // env はまだ誰も参照していない。
+			env.Table[sym] = argsEnv.Eval(args.Car)
+			args = args.Cdr
+		}
+		return
+	}
+	ll.Bind(evalArgs(args, argsEnv), env)
+}
+
+// 評価済みの実引数を仮引数リストに従って新しい環境 env に束縛する。
+// 既定値の式は，それより左の仮引数が束縛された env で評価する。
+func (ll *LambdaList) Bind(args []Any, env *Env) {
+	n := len(args)
+	if n < len(ll.Required) ||
+		(ll.Rest == nil && !ll.HasKey &&
+			n > len(ll.Required)+len(ll.Optional)) {
+		ll.arityError(args)
+	}
+	for i, sym := range ll.Required { // env はまだ誰も参照していない。
+		env.Table[sym] = args[i]
+	}
+	i := len(ll.Required)
+	for _, op := range ll.Optional {
+		if i < n {
+			op.bind(env, args[i], true)
+			i++
+		} else {
+			op.bind(env, env.Eval(op.Default), false)
+		}
+	}
+	if ll.Rest != nil {
+		var rest *Cell
+		for j := n - 1; j >= i; j-- {
+			rest = Cons(args[j], rest)
+		}
+		env.define(ll.Rest, rest)
+	}
+	if ll.HasKey {
+		ll.bindKeys(args[i:], args, env)
+	}
+}
+
+func (ll *LambdaList) bindKeys(rest []Any, args []Any, env *Env) {
+	if len(rest)%2 != 0 {
+		panic(fmt.Errorf("odd number of keyword arguments for %s; given %s",
+			StringFor(ll.Source), StringFor(listFunc(args))))
+	}
+	allowOtherKeys := ll.AllowOtherKeys
+	for i := 0; i < len(rest); i += 2 {
+		if rest[i] == AllowOtherKeysKeyword && rest[i+1] != (*Cell)(nil) {
+			allowOtherKeys = true
+		}
+	}
+	if !allowOtherKeys {
+	check:
+		for i := 0; i < len(rest); i += 2 {
+			if rest[i] == AllowOtherKeysKeyword {
+				continue
+			}
+			for _, kp := range ll.Keys {
+				if rest[i] == kp.Keyword {
+					continue check
+				}
+			}
+			panic(fmt.Errorf("unknown keyword %s for %s; given %s",
+				StringFor(rest[i]), StringFor(ll.Source),
+				StringFor(listFunc(args))))
+		}
+	}
+	for _, kp := range ll.Keys {
+		found := false
+		for i := 0; i < len(rest); i += 2 {
+			if rest[i] == kp.Keyword { // 最左のものを採る
+				kp.bind(env, rest[i+1], true)
+				found = true
+				break
+			}
+		}
+		if !found {
+			kp.bind(env, env.Eval(kp.Default), false)
+		}
+	}
+}
+
+func (op *OptionalParam) bind(env *Env, val Any, supplied bool) {
+	env.define(op.Var, val)
+	if op.Supplied != nil {
+		env.define(op.Supplied, LispBool(supplied))
+	}
+}
+
+func (ll *LambdaList) arityError(args []Any) {
+	panic(fmt.Errorf("arity %s; given %s",
+		StringFor(ll.Source), StringFor(listFunc(args))))
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
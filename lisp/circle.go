@@ -0,0 +1,138 @@
+// H25.5/3 (鈴)
+
+// このファイルは共有構造と循環構造を #n= と #n# のラベルで表す
+// 印字を実装する。例えば (rplacd (cdr x) x) で循環させた (a b) は
+// #1=(a b . #1#) と印字され，そのまま読み込むことができる。
+// リストの途中のセルが共有されているときは，そこから先を
+// . に続けて書く (Cdr は *Cell 型だから . の後は常にリストである)。
+
+package lisp
+
+import (
+	"strconv"
+	"strings"
+)
+
+// この変数の値が真ならば，StringFor 関数はラベル付きで印字する。
+var PrintCircleSymbol = NewSymbol("*print-circle*")
+
+// 変数 *print-circle* の値が真ならば true を返す。
+func PrintCircle() bool {
+	v, ok := Globals.Lookup(PrintCircleSymbol)
+	return ok && v != (*Cell)(nil)
+}
+
+// 共有構造と循環構造にラベルを付けた Lisp 式の文字列表現を返す。
+func StringWithLabelsFor(a Any) string {
+	p := &labelPrinter{make(map[Any]bool), make(map[Any]int), 0}
+	p.scan(a)
+	return p.stringFor(a)
+}
+
+// ラベル付きの印字の状態
+type labelPrinter struct {
+	shared map[Any]bool // 二度以上たどられたセルと構造体
+	labels map[Any]int  // 印字済みのラベル
+	count  int          // 最後に付けたラベルの番号
+}
+
+// 式をたどって共有されているセルと構造体を shared に記録する。
+// seen の代わりに shared の値が false であることで一度目を表す。
+func (p *labelPrinter) scan(a Any) {
+	for {
+		switch x := a.(type) {
+		case *Cell:
+			if x == nil {
+				return
+			}
+			if _, ok := p.shared[x]; ok {
+				p.shared[x] = true
+				return
+			}
+			p.shared[x] = false
+			p.scan(x.Car)
+			a = x.Cdr // Cdr は再帰せずに繰り返す
+		case *Struct:
+			if _, ok := p.shared[x]; ok {
+				p.shared[x] = true
+				return
+			}
+			p.shared[x] = false
+			for _, e := range x.Fields {
+				p.scan(e)
+			}
+			return
+		default:
+			return
+		}
+	}
+}
+
+// 共有されているならば #n# か #n= を返す。
+// #n# を返したときは論理値に true を返す。
+func (p *labelPrinter) label(a Any) (string, bool) {
+	if n, ok := p.labels[a]; ok {
+		return "#" + strconv.Itoa(n) + "#", true
+	}
+	if p.shared[a] {
+		p.count++
+		p.labels[a] = p.count
+		return "#" + strconv.Itoa(p.count) + "=", false
+	}
+	return "", false
+}
+
+func (p *labelPrinter) stringFor(a Any) string {
+	switch x := a.(type) {
+	case *Cell:
+		if x == nil {
+			break
+		}
+		prefix, done := p.label(x)
+		if done {
+			return prefix
+		}
+		if x.Car == QuoteSymbol && x.Cdr != nil && x.Cdr.Cdr == nil &&
+			!p.shared[x.Cdr] {
+			return prefix + "'" + p.stringFor(x.Cdr.Car)
+		}
+		s := []string{p.stringFor(x.Car)}
+		for y := x.Cdr; y != nil; y = y.Cdr {
+			if p.shared[y] {
+				s = append(s, ".", p.stringFor(y))
+				break
+			}
+			s = append(s, p.stringFor(y.Car))
+		}
+		return prefix + "(" + strings.Join(s, " ") + ")"
+	case *Struct:
+		prefix, done := p.label(x)
+		if done {
+			return prefix
+		}
+		return prefix + stringForStruct(x, p.stringFor)
+	}
+	return stringFor(a, MaxPrintRecur, nil)
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,66 @@
+// H25.5/13 (鈴)
+
+package lisp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStringWithLabelsFor(t *testing.T) {
+	x := &Cell{int32(1), &Cell{int32(2), nil}}
+	x.Cdr.Cdr = x
+	y := &Cell{NewSymbol("a"), nil}
+	for _, c := range []struct {
+		a    Any
+		want string
+	}{
+		{x, "#1=(1 2 . #1#)"},
+		{&Cell{y, &Cell{y, nil}}, "(#1=(a) #1#)"},
+		{&Cell{y, &Cell{x, &Cell{y, nil}}}, "(#1=(a) #2=(1 2 . #2#) #1#)"},
+		{&Cell{NewSymbol("a"), &Cell{NewSymbol("a"), nil}}, "(a a)"},
+	} {
+		if got := StringWithLabelsFor(c.a); got != c.want {
+			t.Errorf("got %s, want %s", got, c.want)
+		}
+	}
+}
+
+func TestReadLabels(t *testing.T) {
+	x := NewLex(strings.NewReader("#1=(a #2=(b) #2# . #1#)")).Read().(*Cell)
+	if x.Cdr.Cdr.Cdr != x {
+		t.Errorf("#1# is not the list itself")
+	}
+	if x.Cdr.Car != x.Cdr.Cdr.Car {
+		t.Errorf("#2# is not (b) itself")
+	}
+	defer evalText("(setq *print-circle* nil)")
+	checkEval(t, []evalCase{
+		{"(setq *print-circle* t)", "t"},
+		{"(car (cdr (cdr '#1=(a b . #1#))))", "a"},
+		{"(let ((x (list 1))) (list x x))", "(#1=(1) #1#)"},
+		{"'#2#", "error: undefined label: #2#"},
+	})
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -10,7 +10,6 @@ import (
 	"math/big"
 	"strconv"
 	"strings"
-	"sync"
 )
 
 // 任意の型.
@@ -23,9 +22,13 @@ type Cell struct {
 	Cdr *Cell // improper list にはしない
 }
 
-// シンボル型.  同じ文字列に対してアドレスは常に一意。
+// シンボル型.  同じパッケージの同じ文字列に対してアドレスは常に一意。
 type Symbol struct {
 	string
+	Package *Package    // シンボルが属するパッケージ
+	plist   *Cell       // 属性リスト (symbol.go を見よ)
+	doc     string      // 説明文字列
+	lambda  *LambdaList // defun した関数の仮引数リスト (describe 用)
 }
 
 // 新しい cons セルを作る。
@@ -33,19 +36,9 @@ func Cons(car Any, cdr *Cell) *Cell {
 	return &Cell{car, cdr}
 }
 
-var symbols = make(map[string]*Symbol)
-var lock sync.Mutex
-
-// 文字列からシンボルを作る。
+// 文字列からパッケージ lisp のシンボルを作る。
 func NewSymbol(name string) *Symbol {
-	lock.Lock()
-	sym, ok := symbols[name]
-	if !ok {
-		sym = &Symbol{name}
-		symbols[name] = sym
-	}
-	lock.Unlock()
-	return sym
+	return LispPackage.Intern(name)
 }
 
 // 定義済みのシンボルを用意する。
@@ -53,7 +46,22 @@ func NewSymbol(name string) *Symbol {
 var QuoteSymbol = NewSymbol("quote")
 var TSymbol = NewSymbol("t")
 var NilSymbol = NewSymbol("nil")
+var DotSymbol = NewSymbol(".")
 var AmpRestSymbol = NewSymbol("&rest")
+var AmpOptionalSymbol = NewSymbol("&optional")
+var AmpKeySymbol = NewSymbol("&key")
+var AmpAllowOtherKeysSymbol = NewSymbol("&allow-other-keys")
+var AllowOtherKeysKeyword = Keyword("allow-other-keys")
+
+// 名前に対するキーワード (: で始まるシンボル) を作る。
+func Keyword(name string) *Symbol {
+	return NewSymbol(":" + name)
+}
+
+// キーワードならば true を返す。キーワードは評価すると自分自身になる。
+func (sym *Symbol) IsKeyword() bool {
+	return len(sym.string) > 1 && sym.string[0] == ':'
+}
 
 // デバッグの便宜のための cons セルの文字列表現
 func (cell *Cell) String() string {
@@ -63,12 +71,24 @@ func (cell *Cell) String() string {
 // StringFor 関数で同じリストを再帰的に表示する深さ
 var MaxPrintRecur = 4
 
+// (number->string x :decimal) で表示する小数部の最大の桁数
+var MaxDecimalDigits = 1000
+
+// この変数の値が偽ならば，StringFor 関数は分数に /*=1.2*/ などの
+// 浮動小数点数による近似値の注釈を付けない。
+var PrintApproximationSymbol = NewSymbol("*print-approximation*")
+
 // Lisp 式としての引数の文字列表現を返す。
+// 変数 *print-circle* が真ならば共有構造と循環構造にラベルを付ける。
 func StringFor(a Any) string {
-	return stringFor(a, MaxPrintRecur, make(map[*Cell]bool))
+	if PrintCircle() {
+		return StringWithLabelsFor(a)
+	}
+	return stringFor(a, MaxPrintRecur, make(map[Any]bool))
 }
 
-func stringFor(a Any, recurLevel int, printed map[*Cell]bool) string {
+// printed は表示中のセルと構造体を記録する。
+func stringFor(a Any, recurLevel int, printed map[Any]bool) string {
 	switch x := a.(type) {
 	case *Cell:
 		if x != nil && x.Car == QuoteSymbol {
@@ -78,15 +98,35 @@ func stringFor(a Any, recurLevel int, printed map[*Cell]bool) string {
 		}
 		return "(" + stringForList(x, recurLevel, printed) + ")"
 	case *Symbol:
-		return x.string
+		return stringForSymbol(x)
 	case string:
 		return strconv.Quote(x)
+	case *Struct:
+		if printed[x] { // 自分自身を含む構造体を ... で表す
+			recurLevel--
+			if recurLevel < 0 {
+				return "..."
+			}
+		} else {
+			printed[x] = true
+			recurLevel = MaxPrintRecur
+			defer delete(printed, x)
+		}
+		return stringForStruct(x, func(e Any) string {
+			return stringFor(e, recurLevel, printed)
+		})
+	case float64, *big.Int:
+		return arith.String(x)
 	case *big.Rat:
 		s1 := arith.String(x)
 		s2 := fmt.Sprintf("%v", arith.Float64(x))
+		if v, ok := Globals.Lookup(PrintApproximationSymbol); ok &&
+			v == (*Cell)(nil) {
+			return s1
+		}
 		if s1 == s2 {
 			return s1
-		} else if r, ok := NumberFor(s2); ok && arith.Compare(x, r) == 0 {
+		} else if r, ok := new(big.Rat).SetString(s2); ok && x.Cmp(r) == 0 {
 			return fmt.Sprintf("%s /*=%s*/", s1, s2)
 		} else {
 			return fmt.Sprintf("%s /*~%s*/", s1, s2)
@@ -95,11 +135,12 @@ func stringFor(a Any, recurLevel int, printed map[*Cell]bool) string {
 	return fmt.Sprintf("%v", a)
 }
 
-func stringForList(x *Cell, recurLevel int, printed map[*Cell]bool) string {
+func stringForList(x *Cell, recurLevel int, printed map[Any]bool) string {
 	if x == nil {
 		return ""
 	}
 	s := make([]string, 0, 10)
+	var added []*Cell // このリストで記録したセル
 	var y *Cell
 	for y = x; y != nil; y = y.Cdr {
 		if _, ok := printed[y]; ok {
@@ -110,33 +151,66 @@ func stringForList(x *Cell, recurLevel int, printed map[*Cell]bool) string {
 			}
 		} else {
 			printed[y] = true
+			added = append(added, y)
 			recurLevel = MaxPrintRecur
 		}
 		e := stringFor(y.Car, recurLevel, printed)
 		s = append(s, e)
 	}
 	if y == nil { // 最後まで到達できたならば非循環リストである
-		for y := x; y != nil; y = y.Cdr {
+		for _, y := range added { // 外側で表示中のセルは残す
 			delete(printed, y)
 		}
 	}
 	return strings.Join(s, " ")
 }
 
-// 文字列に対する整数または有理数を得る。変換できないとき論理値に偽を返す。
-func NumberFor(text string) (arith.Number, bool) {
-	i, err := strconv.ParseInt(text, 0, 32)
-	if err == nil {
-		return i, true
+// 二つの Lisp 式が構造的に等しいかどうか判定する。
+// 数は型と値が，文字列は内容が等しいとき，リストと構造体は各要素が
+// 等しいとき等しいとする。
+func Equal(a, b Any) bool {
+	if a == b {
+		return true
 	}
-	r := new(big.Rat)
-	n := new(big.Int)
-	_, ok := n.SetString(text, 0) // これは 0x… 0b… 0… を解釈する
-	if ok {
-		r.SetInt(n)
-		return r, true
+	switch x := a.(type) {
+	case *Cell:
+		y, ok := b.(*Cell)
+		if !ok {
+			return false
+		}
+		for ; x != nil && y != nil; x, y = x.Cdr, y.Cdr {
+			if x == y {
+				return true
+			}
+			if !Equal(x.Car, y.Car) {
+				return false
+			}
+		}
+		return x == y
+	case *Struct:
+		y, ok := b.(*Struct)
+		if !ok || x.Type != y.Type {
+			return false
+		}
+		for i := range x.Fields {
+			if !Equal(x.Fields[i], y.Fields[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if arith.IsNumber(a) && arith.IsNumber(b) {
+		return fmt.Sprintf("%T", a) == fmt.Sprintf("%T", b) &&
+			arith.Compare(a, b) == 0
 	}
-	return r.SetString(text) //  これは 0x… 0b… 0… を解釈しない
+	return false
+}
+
+// 文字列に対する整数，有理数または浮動小数点数を得る。
+// 文法は arith.Parse に従う。変換できないとき論理値に偽を返す。
+func NumberFor(text string) (arith.Number, bool) {
+	n, err := arith.Parse(text)
+	return n, err == nil
 }
 
 /*
@@ -0,0 +1,380 @@
+// H25.5/12 (鈴)
+
+// このファイルはエラーのときに入るブレーク・ループ (デバッガ) を実装する。
+// デバッガを有効にした後に defun した関数の中でエラーが起きると，最も
+// 内側の関数の呼び出しの中でブレーク・ループに入る。このとき呼び出し中の
+// 関数はまだ巻き戻されていないから，その環境で式を評価したり，その
+// 呼び出しから値を返したりできる。呼び出し元はプロファイラと同じく
+// 呼び出した式を評価した環境からたどる。
+// ブレーク・ループの中でさらにエラーが起きると入れ子のループに入る。
+// 末尾呼び出しで終わった関数の呼び出しは呼び出し元の列から除くから，
+// デバッガを有効にしても末尾呼び出しはスタックを消費しない。
+
+package lisp
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/scanner"
+)
+
+// デバッガ
+type debugger struct {
+	readLine func(prompt string) (string, error)
+	out      io.Writer
+	level    int32 // ブレーク・ループの入れ子の深さ
+}
+
+// 有効なデバッガ. 無効ならば nil
+var currentDebugger atomic.Pointer[debugger]
+
+// デバッガを有効にする。これより後に defun した関数の中でエラーが
+// 起きると，readLine で入力を読み out に出力するブレーク・ループに入る。
+// readLine は入力の終わりで io.EOF を返すこと。
+func EnableDebugger(readLine func(prompt string) (string, error), out io.Writer) {
+	if currentDebugger.Swap(&debugger{readLine: readLine, out: out}) == nil {
+		endDebugger = beginSlowPath()
+	}
+}
+
+// デバッガが有効な間の評価の遅い経路を終える関数
+var endDebugger func()
+
+// デバッガを無効にする。
+func disableDebugger() {
+	if currentDebugger.Swap(nil) != nil {
+		endDebugger()
+	}
+}
+
+// 関数の呼び出し
+type debugFrame struct {
+	name    *Symbol
+	args    []Any
+	env     *Env // 呼び出しの環境
+	argsEnv *Env // 引数を評価した (呼び出し元の) 環境
+	parent  *debugFrame
+}
+
+// 関数の呼び出しを環境に記録するための (intern されない) シンボル
+var debugFrameSymbol = &Symbol{string: "#<debug frame>"}
+
+// ブレーク・ループからの中止. 一つ外側のブレーク・ループまたはトップ
+// レベルに戻る。
+type debugAbort struct {
+	err   Any
+	level int32 // 中止したブレーク・ループの深さ
+}
+
+func (a *debugAbort) Error() string {
+	return "aborted: " + errorString(a.err)
+}
+
+// ブレーク・ループからの関数の呼び出しの戻り
+type debugReturn struct {
+	frame *debugFrame
+	value Any
+}
+
+func (r *debugReturn) Error() string {
+	return "return from inactive frame: " + r.frame.String()
+}
+
+// パニックの値の文字列表現
+func errorString(r Any) string {
+	if e, ok := r.(error); ok {
+		return e.Error()
+	}
+	return fmt.Sprint(r)
+}
+
+// 呼び出しの文字列表現 (表示用)
+func (fr *debugFrame) String() string {
+	return StringFor(Cons(fr.name, listFunc(fr.args).(*Cell)))
+}
+
+// 名前 name の関数の lambda 式の本体 b を，エラーのときブレーク・ループに
+// 入るように評価する関数を返す。デバッガが無効ならば nil を返す。
+// 関数は呼び出しを環境に束縛し，末尾式は普通の関数と同じく環境と共に
+// 返す。末尾式より前のエラーはこの関数が，末尾式のエラーは呼び出した
+// 式を評価している debugEval が受け取る。
+func debuggedBody(name *Symbol, ll *LambdaList, b *Cell, lambdaEnv *Env) func(*Cell, *Env) (Any, *Env) {
+	d := currentDebugger.Load()
+	if d == nil {
+		return nil
+	}
+	return func(args *Cell, argsEnv *Env) (val Any, tailEnv *Env) {
+		env := newCallEnv(lambdaEnv, argsEnv)
+		argv := evalArgs(args, argsEnv)
+		fr := &debugFrame{name: name, args: argv, env: env, argsEnv: argsEnv,
+			parent: frameOf(argsEnv)}
+		env.define(debugFrameSymbol, fr)
+		defer func() {
+			if r := recover(); r != nil {
+				val, tailEnv = d.handle(fr, fr, r), nil
+			}
+		}()
+		ll.Bind(argv, env)
+		return prognForm(b, env)
+	}
+}
+
+// 環境から最も内側の関数の呼び出しを返す。無ければ nil を返す。
+func frameOf(env *Env) *debugFrame {
+	if v, ok := env.Lookup(debugFrameSymbol); ok {
+		return v.(*debugFrame)
+	}
+	return nil
+}
+
+// debugEval が評価している関数の呼び出し
+type debugCursor struct {
+	env   *Env        // 評価を始めたときの環境
+	moved bool        // 呼び出しを調べたか?
+	start *debugFrame // 評価を始めたときの呼び出し
+	frame *debugFrame // 現在の呼び出し
+}
+
+// 末尾式を評価する環境 env に移る。末尾呼び出しで現在の呼び出しが
+// 終わるならば，新しい呼び出しの呼び出し元を付け替えて古い呼び出しを
+// 忘れる。
+func (c *debugCursor) step(env *Env) {
+	if !c.moved { // 呼び出しは末尾式に移るときまで調べない。
+		c.start = frameOf(c.env)
+		c.frame, c.moved = c.start, true
+	}
+	fr := frameOf(env)
+	if fr == c.frame {
+		return
+	}
+	if fr != nil && c.frame != c.start && fr.parent == c.frame {
+		fr.parent = c.frame.parent
+	}
+	c.frame = fr
+}
+
+// デバッガが有効なときの評価. エラーのとき最も内側の呼び出しの中で
+// ブレーク・ループに入る。評価中に末尾呼び出しで入った呼び出しからは
+// 値を返せる。
+func (env *Env) debugEval(a Any, d *debugger) (val Any) {
+	if _, ok := a.(*Cell); !ok {
+		return env.eval(a, nil)
+	}
+	cur := &debugCursor{env: env}
+	defer func() {
+		if r := recover(); r != nil {
+			fr, owner := frameOf(env), (*debugFrame)(nil)
+			if cur.moved {
+				fr = cur.frame
+				if fr != cur.start {
+					owner = fr
+				}
+			}
+			val = d.handle(fr, owner, r)
+		}
+	}()
+	return env.eval(a, cur)
+}
+
+// 呼び出し fr の中のパニック r を処理する。owner はこの処理から値を
+// 返せる呼び出しまたは nil である。owner から値を返すならばその値を
+// 返し，さもなくばパニックする。
+func (d *debugger) handle(fr, owner *debugFrame, r Any) Any {
+	switch x := r.(type) {
+	case *debugReturn:
+		if x.frame == owner {
+			return x.value
+		}
+		panic(r)
+	case *debugAbort:
+		panic(r)
+	}
+	if fr == nil { // 関数の外のエラー
+		panic(r)
+	}
+	ret := d.breakLoop(fr, r)
+	if ret.frame == owner {
+		return ret.value
+	}
+	panic(ret)
+}
+
+// ブレーク・ループの状態
+type breakState struct {
+	d        *debugger
+	level    int32
+	err      Any
+	frames   []*debugFrame // 内側から順の呼び出し
+	selected int
+}
+
+// 呼び出し fr の中のエラー r についてブレーク・ループを行う。
+func (d *debugger) breakLoop(fr *debugFrame, r Any) *debugReturn {
+	level := atomic.AddInt32(&d.level, 1)
+	defer atomic.AddInt32(&d.level, -1)
+	st := &breakState{d: d, level: level, err: r}
+	for f := fr; f != nil; f = f.parent {
+		st.frames = append(st.frames, f)
+	}
+	fmt.Fprintf(d.out, "==> %s\n", errorString(r))
+	st.backtrace()
+	fmt.Fprintln(d.out, "Type :help for debugger commands.")
+	prompt := "debug> "
+	if level > 1 {
+		prompt = fmt.Sprintf("debug[%d]> ", level)
+	}
+	for {
+		line, err := d.readLine(prompt)
+		if err == io.EOF {
+			panic(&debugAbort{r, level})
+		} else if err != nil {
+			continue
+		}
+		if ret := st.command(line); ret != nil {
+			return ret
+		}
+	}
+}
+
+// ブレーク・ループの一行を実行する。呼び出しから値を返すならば，その
+// 呼び出しと値を返し，さもなくば nil を返す。
+func (st *breakState) command(line string) (ret *debugReturn) {
+	out := st.d.out
+	defer func() {
+		if r := recover(); r != nil {
+			switch x := r.(type) {
+			case *debugReturn:
+				ret = x
+			case *debugAbort:
+				if x.level <= st.level { // このループからの中止
+					panic(r)
+				}
+				fmt.Fprintf(out, "; back to debug level %d\n", st.level)
+			default:
+				fmt.Fprintf(out, "==> %s\n", errorString(r))
+			}
+		}
+	}()
+	name, arg := splitMetaCommand(line)
+	frame := st.frames[st.selected]
+	env := st.env()
+	switch name {
+	case "":
+	case ":help":
+		fmt.Fprint(out, debugHelp)
+	case ":bt", ":backtrace":
+		st.backtrace()
+	case ":f", ":frame":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 || n >= len(st.frames) {
+			panic(fmt.Errorf("frame number expected: %s", arg))
+		}
+		st.selected = n
+		fmt.Fprintf(out, "%d: %s\n", n, st.frames[n])
+		st.locals()
+	case ":l", ":locals":
+		st.locals()
+	case ":r", ":return":
+		val := Any((*Cell)(nil))
+		if arg != "" {
+			val = evalString(arg, env)
+		}
+		return &debugReturn{frame, val}
+	case ":a", ":abort":
+		panic(&debugAbort{st.err, st.level})
+	default:
+		val := evalString(line, env)
+		fmt.Fprintf(out, "=> %s\n", StringFor(val))
+	}
+	return nil
+}
+
+// 文字列の式を環境 env で評価し，最後の式の値を返す。
+func evalString(s string, env *Env) Any {
+	lex := NewLex(strings.NewReader(s))
+	var val Any = (*Cell)(nil)
+	for lex.Token != scanner.EOF {
+		x := lex.Read()
+		if x == nil {
+			panic(fmt.Errorf("incomplete expression: %s", s))
+		}
+		val = env.Eval(x)
+	}
+	return val
+}
+
+// 呼び出し中の関数を内側から順に表示する。選んだ呼び出しには * を付ける。
+func (st *breakState) backtrace() {
+	for i, fr := range st.frames {
+		mark := " "
+		if i == st.selected {
+			mark = "*"
+		}
+		fmt.Fprintf(st.d.out, "%s%d: %s\n", mark, i, fr)
+	}
+}
+
+// 選んだ呼び出しの式を評価する環境を返す。外側の呼び出しでは内側の
+// 関数を呼び出した (let などの中の) 環境とする。
+func (st *breakState) env() *Env {
+	if st.selected > 0 {
+		return st.frames[st.selected-1].argsEnv
+	}
+	return st.frames[0].env
+}
+
+// 選んだ呼び出しの局所変数を内側の環境から順に表示する。
+func (st *breakState) locals() {
+	for env := st.env(); env != nil && env != Globals; env = env.Next {
+		env.Lock.Lock()
+		var syms []*Symbol
+		for sym := range env.Table {
+			if sym != debugFrameSymbol && sym != frameSymbol &&
+				sym != depthSymbol && sym != moduleNameSymbol {
+				syms = append(syms, sym)
+			}
+		}
+		env.Lock.Unlock()
+		sort.Slice(syms, func(i, j int) bool {
+			return syms[i].string < syms[j].string
+		})
+		for _, sym := range syms {
+			val, _ := env.Lookup(sym)
+			fmt.Fprintf(st.d.out, "  %s = %s\n", StringFor(sym), StringFor(val))
+		}
+	}
+}
+
+const debugHelp = `:bt             Show the active calls; * marks the selected one.
+:frame n        Select the call n and show its local variables.
+:locals         Show the local variables of the selected call.
+:return [expr]  Return the value of expr (default nil) from the selected call.
+:abort          Go back to the previous debug level or the top level.
+expr...         Evaluate expressions in the selected call.
+`
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
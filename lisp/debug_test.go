@@ -0,0 +1,102 @@
+// H25.5/13 (鈴)
+
+package lisp
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// 行 lines を順に読むデバッガを有効にし，f を呼んだ後に無効にする。
+// デバッガの出力を返す。
+func withDebugger(lines []string, f func()) string {
+	var out bytes.Buffer
+	EnableDebugger(func(prompt string) (string, error) {
+		if len(lines) == 0 {
+			return "", io.EOF
+		}
+		line := lines[0]
+		lines = lines[1:]
+		out.WriteString(prompt + line + "\n")
+		return line, nil
+	}, &out)
+	defer disableDebugger()
+	f()
+	return out.String()
+}
+
+func TestDebuggerTailCall(t *testing.T) {
+	withDebugger(nil, func() {
+		checkEval(t, []evalCase{
+			{`(defun dbg-loop (n acc)
+			    (if (= n 0) acc (dbg-loop (- n 1) (+ acc 1))))
+			  (dbg-loop 3000000 0)`, "3000000"},
+		})
+	})
+}
+
+func TestDebuggerReturn(t *testing.T) {
+	out := withDebugger([]string{":bt", ":frame 1", ":return y"}, func() {
+		checkEval(t, []evalCase{
+			{`(defun dbg-g (n) (+ n (car n)))
+			  (defun dbg-f (x) (let ((y (* x 2))) (list (dbg-g y))))
+			  (dbg-f 3)`, "6"},
+		})
+	})
+	for _, want := range []string{
+		"*0: (dbg-g 6)\n 1: (dbg-f 3)\n",
+		"debug> :frame 1\n1: (dbg-f 3)\n  y = 6\n  x = 3\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q does not contain %q", out, want)
+		}
+	}
+}
+
+func TestDebuggerTailFrame(t *testing.T) {
+	out := withDebugger([]string{":return 7"}, func() {
+		checkEval(t, []evalCase{
+			{`(defun dbg-h (x) (let ((y (* x 2))) (dbg-g y)))
+			  (list (dbg-h 3))`, "(7)"},
+		})
+	})
+	if !strings.Contains(out, "*0: (dbg-g 6)\nType") {
+		t.Errorf("tail call frame is not removed: %q", out)
+	}
+}
+
+func TestDebuggerAbort(t *testing.T) {
+	out := withDebugger([]string{"(car 1)", ":abort", ":abort"}, func() {
+		checkEval(t, []evalCase{
+			{"(dbg-g 1)", "error: aborted: " +
+				"interface conversion: lisp.Any is int32, not *lisp.Cell"},
+		})
+	})
+	if !strings.Contains(out, "; back to debug level 1\n") {
+		t.Errorf("nested abort is not reported: %q", out)
+	}
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,330 @@
+// H25.5/10 (鈴)
+
+// このファイルは組込みの関数と変数の説明文字列と，対話セッションで
+// 使うための describe と apropos を実装する。
+// defun の本体の先頭の文字列は説明文字列としてシンボルに付ける。
+
+package lisp
+
+import (
+	"github.com/pkelchte/tiny-lisp/arith"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// 組込みの関数と変数の説明文字列. init でシンボルに付ける。
+var builtinDocs = map[string]string{
+	"t":                     "The canonical true value.",
+	"car":                   "(car list) Return the first element of list.",
+	"cdr":                   "(cdr list) Return list without its first element.",
+	"cons":                  "(cons x list) Return a new cell whose car is x and cdr is list.",
+	"listp":                 "(listp x) Return t if x is a list (including nil).",
+	"eq":                    "(eq x y) Return t if x and y are the identical object.",
+	"equal":                 "(equal x y) Return t if x and y are structurally equal.",
+	"rplaca":                "(rplaca cell x) Replace the car of cell with x.",
+	"rplacd":                "(rplacd cell list) Replace the cdr of cell with list.",
+	"list":                  "(list x...) Return a list of the arguments.",
+	"=":                     "(= x y) Return t if the numbers x and y are equal.",
+	"/=":                    "(/= x y) Return t if the numbers x and y are not equal.",
+	"<":                     "(< x y) Return t if the number x is less than y.",
+	"<=":                    "(<= x y) Return t if the number x is less than or equal to y.",
+	">":                     "(> x y) Return t if the number x is greater than y.",
+	">=":                    "(>= x y) Return t if the number x is greater than or equal to y.",
+	"+":                     "(+ x...) Return the sum of the numbers.",
+	"-":                     "(- x y...) Return x minus the rest, or the negation of x.",
+	"*":                     "(* x...) Return the product of the numbers.",
+	"/":                     "(/ x y...) Return x divided by the rest, or the reciprocal of x.",
+	"quotient":              "(quotient x y) Return the integer quotient truncated toward zero.",
+	"remainder":             "(remainder x y) Return the remainder with the sign of x.",
+	"mod":                   "(mod x y) Return the modulus with the sign of y.",
+	"truncate":              "(truncate x [divisor]) Round x/divisor toward zero.",
+	"floor":                 "(floor x [divisor]) Round x/divisor toward negative infinity.",
+	"ceiling":               "(ceiling x [divisor]) Round x/divisor toward positive infinity.",
+	"round":                 "(round x [divisor]) Round x/divisor to the nearest integer, ties to even.",
+	"abs":                   "(abs x) Return the absolute value of x.",
+	"min":                   "(min x y...) Return the smallest number.",
+	"max":                   "(max x y...) Return the largest number.",
+	"gcd":                   "(gcd n...) Return the greatest common divisor of the integers.",
+	"lcm":                   "(lcm n...) Return the least common multiple of the integers.",
+	"expt":                  "(expt x y) Return x raised to the power y.",
+	"numerator":             "(numerator q) Return the numerator of the rational q.",
+	"denominator":           "(denominator q) Return the denominator of the rational q.",
+	"exact->inexact":        "(exact->inexact x) Convert x to a float.",
+	"inexact->exact":        "(inexact->exact x) Convert x to an exact rational.",
+	"integerp":              "(integerp x) Return t if x is an integer.",
+	"rationalp":             "(rationalp x) Return t if x is an integer or a ratio.",
+	"floatp":                "(floatp x) Return t if x is a float.",
+	"numberp":               "(numberp x) Return t if x is a number.",
+	"pi":                    "The ratio of a circle's circumference to its diameter.",
+	"e":                     "The base of the natural logarithm.",
+	"sqrt":                  "(sqrt x) Return the square root of x.",
+	"exp":                   "(exp x) Return e raised to the power x.",
+	"log":                   "(log x [base]) Return the logarithm of x, natural by default.",
+	"sin":                   "(sin x) Return the sine of x radians.",
+	"cos":                   "(cos x) Return the cosine of x radians.",
+	"tan":                   "(tan x) Return the tangent of x radians.",
+	"asin":                  "(asin x) Return the arc sine of x.",
+	"acos":                  "(acos x) Return the arc cosine of x.",
+	"atan":                  "(atan y [x]) Return the arc tangent of y or of y/x.",
+	"sinh":                  "(sinh x) Return the hyperbolic sine of x.",
+	"cosh":                  "(cosh x) Return the hyperbolic cosine of x.",
+	"tanh":                  "(tanh x) Return the hyperbolic tangent of x.",
+	"logand":                "(logand n...) Return the bitwise and of the integers.",
+	"logior":                "(logior n...) Return the bitwise inclusive or of the integers.",
+	"logxor":                "(logxor n...) Return the bitwise exclusive or of the integers.",
+	"lognot":                "(lognot n) Return the bitwise complement of n.",
+	"ash":                   "(ash n count) Shift n left by count bits, or right if count is negative.",
+	"integer-length":        "(integer-length n) Return the number of bits needed to represent n.",
+	"bit-count":             "(bit-count n) Return the number of one bits in n (zero bits if n is negative).",
+	"isqrt":                 "(isqrt n) Return the integer square root of n.",
+	"expt-mod":              "(expt-mod base exponent modulus) Return base^exponent mod modulus.",
+	"prime-p":               "(prime-p n) Return t if n is prime.",
+	"random":                "(random limit [state]) Return a random number in [0, limit).",
+	"make-random-state":     "(make-random-state [seed]) Return a new random state.",
+	"gensym":                "(gensym) Return a fresh symbol.",
+	"print":                 "(print x) Write x followed by a newline to *standard-output*.",
+	"pprint":                "(pprint x) Pretty print x to *standard-output*.",
+	"format":                "(format destination control arg...) Format the arguments by the control string.",
+	"*standard-output*":     "The stream that output functions write to by default.",
+	"*standard-input*":      "The stream that input functions read from by default.",
+	"open-input-file":       "(open-input-file filename) Open a file for reading.",
+	"open-output-file":      "(open-output-file filename) Create a file for writing.",
+	"close":                 "(close stream) Close the stream.",
+	"with-open-file":        "(with-open-file (var filename [:direction dir]) body...) Evaluate body with the file open.",
+	"read-line":             "(read-line [stream]) Read a line, or return nil at end of file.",
+	"read-char":             "(read-char [stream]) Read a character, or return nil at end of file.",
+	"write-string":          "(write-string string [stream]) Write string without quotes.",
+	"write":                 "(write x [stream]) Write x in a form that read accepts.",
+	"display":               "(display x [stream]) Write x, strings without quotes.",
+	"newline":               "(newline [stream]) Write a newline.",
+	"with-output-to-string": "(with-output-to-string ([var]) body...) Return what body writes as a string.",
+	"read":                  "(read [stream-or-string [eof-error-p [eof-value]]]) Read an expression.",
+	"read-from-string":      "(read-from-string string [eof-error-p [eof-value]]) Read an expression from string.",
+	"eval":                  "(eval x [environment]) Evaluate x.",
+	"the-environment":       "(the-environment) Return the current environment.",
+	"load":                  "(load filename) Evaluate the expressions in the file.",
+	"require":               "(require name [filename]) Load the module unless it has been provided.",
+	"provide":               "(provide name) Mark the module as loaded.",
+	"module":                "(module name [(export symbol...)] body...) Evaluate body in a private environment.",
+	"*load-path*":           "The directories that load and require search.",
+	"*load-pathname*":       "The name of the file being loaded, or nil.",
+	"*modules*":             "The names of the provided modules.",
+	"trace":                 "(trace name...) Trace calls to the functions; with no names, return the traced ones.",
+	"untrace":               "(untrace name...) Stop tracing the functions, or all functions.",
+	"*trace-output*":        "The stream for trace output, or nil for *standard-output*.",
+	"exit":                  "(exit [code]) End the process with the exit code (default 0).",
+	"getenv":                "(getenv name) Return the value of an environment variable, or nil.",
+	"*argv*":                "The command-line arguments given to the script.",
+	"**":                    "The second most recent result in the interactive session.",
+	"***":                   "The third most recent result in the interactive session.",
+	"++":                    "The second most recent input in the interactive session.",
+	"+++":                   "The third most recent input in the interactive session.",
+	"defpackage":            "(defpackage name [(:use package...)] [(:export symbol...)]) Define a package.",
+	"in-package":            "(in-package name) Make the package current.",
+	"export":                "(export symbols [package]) Make the symbols external in the package.",
+	"find-package":          "(find-package name) Return the package, or nil.",
+	"intern":                "(intern string [package]) Return the symbol named string, creating it if needed.",
+	"symbol-name":           "(symbol-name symbol) Return the name of symbol.",
+	"symbol-package":        "(symbol-package symbol) Return the home package of symbol.",
+	"package-name":          "(package-name package) Return the name of package.",
+	"*package*":             "The current package used when reading symbols.",
+	"get":                   "(get symbol indicator [default]) Return a property of symbol.",
+	"put":                   "(put symbol indicator value) Set a property of symbol.",
+	"remprop":               "(remprop symbol indicator) Remove a property of symbol.",
+	"symbol-plist":          "(symbol-plist symbol) Return the property list of symbol.",
+	"symbol-value":          "(symbol-value symbol) Return the global value of symbol.",
+	"symbol-function":       "(symbol-function symbol) Return the global function of symbol.",
+	"boundp":                "(boundp symbol) Return t if symbol has a global value.",
+	"fboundp":               "(fboundp symbol) Return t if symbol names a global function.",
+	"makunbound":            "(makunbound symbol) Remove the global value of symbol.",
+	"documentation":         "(documentation symbol) Return the documentation string of symbol, or nil.",
+	"set-documentation":     "(set-documentation symbol string) Set the documentation string of symbol.",
+	"describe":              "(describe x) Print a description of x.",
+	"apropos":               "(apropos string) Print the symbols whose names contain string.",
+	"*print-circle*":        "If true, print shared and circular structure with #n= and #n# labels.",
+	"*print-pretty*":        "If true, the interactive session pretty prints its results.",
+	"*print-right-margin*":  "The line width that the pretty printer fills to.",
+	"*print-approximation*": "If true, print ratios with their approximate float values.",
+	"quote":                 "(quote x) Return x unevaluated.",
+	"setq":                  "(setq var x) Set the variable var to the value of x.",
+	"progn":                 "(progn x...) Evaluate the expressions and return the last value.",
+	"if":                    "(if test then else...) Evaluate then if test is true, else the rest.",
+	"lambda":                "(lambda (param...) [doc] body...) Return a function.",
+	"let":                   "(let ((var x)...) body...) Evaluate body with local variables.",
+	"defun":                 "(defun name (param...) [doc] body...) Define a global function.",
+	"apply":                 "(apply function list) Call function with the elements of list.",
+	"and":                   "(and x...) Return nil at the first false x, or the last value.",
+	"future":                "(future x) Start evaluating x concurrently and return a future.",
+	"force":                 "(force future) Wait for the future and return its value.",
+	"defstruct":             "(defstruct name field...) Define a structure type and its functions.",
+	"number->string":        "(number->string x [radix | :decimal [digits] | :fixed digits | :scientific digits]) Format a number.",
+}
+
+func init() {
+	for name, doc := range builtinDocs {
+		NewSymbol(name).SetDocumentation(doc)
+	}
+}
+
+// 本体の先頭の文字列を説明文字列として分ける。
+// 本体が文字列ひとつだけならば，それは説明文字列ではなく値とする。
+func splitDocString(body *Cell) (string, *Cell) {
+	if body != nil && body.Cdr != nil {
+		if doc, ok := body.Car.(string); ok {
+			return doc, body.Cdr
+		}
+	}
+	return "", body
+}
+
+// 値の種類を表す名前を返す。
+func typeNameFor(a Any) string {
+	switch x := a.(type) {
+	case *Cell:
+		if x == nil {
+			return "empty list"
+		}
+		return "list"
+	case *Symbol:
+		if x.IsKeyword() {
+			return "keyword"
+		}
+		return "symbol"
+	case int32, *big.Int:
+		return "integer"
+	case *big.Rat:
+		return "ratio"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case *Struct:
+		return "structure of type " + x.Type.Name.string
+	case func(*Cell, *Env) (Any, *Env):
+		return "special form or function"
+	case func([]Any) Any, func([]Any, *Env) Any:
+		return "built-in function"
+	case *Stream:
+		return "stream"
+	case *Package:
+		return "package"
+	case *Env:
+		return "environment"
+	case *Future:
+		return "future"
+	case *arith.RandomState:
+		return "random state"
+	}
+	return fmt.Sprintf("%T", a)
+}
+
+// 名詞に不定冠詞を付ける。
+func withArticle(noun string) string {
+	if strings.ContainsRune("aeiou", rune(noun[0])) {
+		return "an " + noun
+	}
+	return "a " + noun
+}
+
+// 値の説明を w に書く。シンボルならばその値と説明文字列と属性も書き，
+// 値が defun した関数ならば仮引数リストも書く。
+func Describe(w io.Writer, a Any) {
+	if IsFunction(a) {
+		fmt.Fprintln(w, withArticle(typeNameFor(a)))
+		return
+	}
+	fmt.Fprintf(w, "%s is %s\n", StringFor(a), withArticle(typeNameFor(a)))
+	sym, ok := a.(*Symbol)
+	if !ok || sym.IsKeyword() {
+		return
+	}
+	if sym.Package != nil {
+		fmt.Fprintf(w, "  package: %s\n", sym.Package.Name)
+	}
+	if val, ok := Globals.Lookup(sym); ok {
+		if IsFunction(val) {
+			fmt.Fprintf(w, "  function: %s\n", withArticle(typeNameFor(val)))
+			if ll := sym.lambdaList(); ll != nil {
+				fmt.Fprintf(w, "  lambda list: %s\n", StringFor(ll.Source))
+			}
+		} else {
+			fmt.Fprintf(w, "  value: %s\n", StringFor(val))
+		}
+	} else {
+		fmt.Fprintf(w, "  unbound\n")
+	}
+	if doc := sym.Documentation(); doc != "" {
+		fmt.Fprintf(w, "  documentation: %s\n", doc)
+	}
+	if plist := sym.Plist(); plist != nil {
+		fmt.Fprintf(w, "  properties: %s\n", StringFor(plist))
+	}
+}
+
+// 名前が s を含むシンボルを名前の順に返す。大文字と小文字は区別しない。
+func Apropos(s string) []*Symbol {
+	s = strings.ToLower(s)
+	seen := make(map[*Symbol]bool)
+	var result []*Symbol
+	for _, p := range Packages() {
+		for _, sym := range p.Symbols() {
+			if !seen[sym] && strings.Contains(strings.ToLower(sym.string), s) {
+				seen[sym] = true
+				result = append(result, sym)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return stringForSymbol(result[i]) < stringForSymbol(result[j])
+	})
+	return result
+}
+
+// (describe x)
+func describeFunc(a []Any, env *Env) Any {
+	CheckArity(1, a)
+	Describe(standardOutput(env), a[0])
+	return (*Cell)(nil)
+}
+
+// (apropos string) 該当するシンボルを一行ずつ印字する。
+// 大域的に束縛されていれば function か variable と付記する。
+func aproposFunc(a []Any, env *Env) Any {
+	CheckArity(1, a)
+	w := standardOutput(env)
+	for _, sym := range Apropos(a[0].(string)) {
+		if val, ok := Globals.Lookup(sym); ok && IsFunction(val) {
+			fmt.Fprintf(w, "%s (function)\n", stringForSymbol(sym))
+		} else if ok {
+			fmt.Fprintf(w, "%s (variable)\n", stringForSymbol(sym))
+		} else {
+			fmt.Fprintln(w, stringForSymbol(sym))
+		}
+	}
+	return (*Cell)(nil)
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
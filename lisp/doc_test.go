@@ -0,0 +1,79 @@
+// H25.5/13 (鈴)
+
+package lisp
+
+import "testing"
+
+func TestDocStrings(t *testing.T) {
+	checkEval(t, []evalCase{
+		{"(documentation 'car)", `"(car list) Return the first element of list."`},
+		{"(documentation '<)", `"(< x y) Return t if the number x is less than y."`},
+		{`(defun dc-sq (x &optional y) "Return x times x." (* x x))
+		  (documentation 'dc-sq)`, `"Return x times x."`},
+		{"(defun dc-nodoc () 1) (documentation 'dc-nodoc)", "()"},
+	})
+}
+
+func TestDescribe(t *testing.T) {
+	checkEval(t, []evalCase{
+		{`(defun dc-sq (x &optional y) "Return x times x." (* x x))
+		  (with-output-to-string () (describe 'dc-sq))`,
+			`"dc-sq is a symbol\n  package: user\n` +
+				`  function: a special form or function\n` +
+				`  lambda list: (x &optional y)\n` +
+				`  documentation: Return x times x.\n"`},
+		{"(defun dc-none () 1) (with-output-to-string () (describe 'dc-none))",
+			`"dc-none is a symbol\n  package: user\n` +
+				`  function: a special form or function\n  lambda list: ()\n"`},
+		{"(with-output-to-string () (describe 'cdr))",
+			`"cdr is a symbol\n  package: lisp\n  function: a built-in function\n` +
+				`  documentation: (cdr list) Return list without its first element.\n"`},
+		{`(setq dc-v 3) (put 'dc-v 'color "red")
+		  (with-output-to-string () (describe 'dc-v))`,
+			`"dc-v is a symbol\n  package: user\n  value: 3\n` +
+				`  properties: (color \"red\")\n"`},
+		{"(with-output-to-string () (describe 'dc-unbound))",
+			`"dc-unbound is a symbol\n  package: user\n  unbound\n"`},
+		{"(with-output-to-string () (describe 7) (describe :dc-k))",
+			`"7 is an integer\n:dc-k is a keyword\n"`},
+	})
+}
+
+func TestApropos(t *testing.T) {
+	checkEval(t, []evalCase{
+		{`(defun dc-apropos-f () 1) (setq dc-apropos-v 2) 'dc-apropos-s
+		  (with-output-to-string () (apropos "DC-APROPOS"))`,
+			`"dc-apropos-f (function)\ndc-apropos-s\ndc-apropos-v (variable)\n"`},
+		{`(with-output-to-string () (apropos "dc-apropos-none"))`, `""`},
+	})
+	var got []string
+	for _, sym := range Apropos("with-output-to") {
+		got = append(got, stringForSymbol(sym))
+	}
+	if len(got) != 1 || got[0] != "with-output-to-string" {
+		t.Errorf("Apropos(%q) => %q", "with-output-to", got)
+	}
+}
+
+/*
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
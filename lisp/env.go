@@ -7,6 +7,7 @@ package lisp
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 )
 
 // 環境. 現在の束縛変数に対する Table と自由変数に対する Next からなる。
@@ -30,8 +31,22 @@ func (env *Env) Get(sym *Symbol) Any {
 	panic(fmt.Errorf("unbound symbol: %s", sym.string))
 }
 
+// シンボルに対する値を環境から探す。無ければ論理値に偽を返す。
+func (env *Env) Lookup(sym *Symbol) (Any, bool) {
+	for ; env != nil; env = env.Next {
+		env.Lock.Lock()
+		val, ok := env.Table[sym]
+		env.Lock.Unlock()
+		if ok {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
 // シンボルに対する値を環境にセットする。
-// 未定義のシンボルをトップレベル以外でセットするとパニックする。
+// 未定義のシンボルはトップレベルまたはモジュールの環境ならば新しく束縛し，
+// それ以外でセットするとパニックする。
 func (env *Env) Set(sym *Symbol, val Any) {
 	for ev := env; ev != nil; ev = ev.Next {
 		ev.Lock.Lock()
@@ -43,7 +58,7 @@ func (env *Env) Set(sym *Symbol, val Any) {
 		}
 		ev.Lock.Unlock()
 	}
-	if env.Next == nil { // トップレベルの環境ならば
+	if env.Next == nil || moduleOf(env) != nil { // トップレベルの環境ならば
 		env.Lock.Lock()
 		env.Table[sym] = val
 		env.Lock.Unlock()
@@ -52,8 +67,121 @@ func (env *Env) Set(sym *Symbol, val Any) {
 	panic(fmt.Errorf("global symbol created locally: %s", sym.string))
 }
 
+// 環境の文字列表現 (表示用)
+func (env *Env) String() string {
+	if env == Globals {
+		return "#<environment global>"
+	}
+	return "#<environment>"
+}
+
+// シンボルに対する値を環境 (の先頭の表) に新しく束縛する。
+func (env *Env) define(sym *Symbol, val Any) {
+	env.Lock.Lock()
+	env.Table[sym] = val
+	env.Lock.Unlock()
+}
+
+// シンボルの束縛を環境 (の先頭の表) から取り除く。
+func (env *Env) undefine(sym *Symbol) {
+	env.Lock.Lock()
+	delete(env.Table, sym)
+	env.Lock.Unlock()
+}
+
+// 評価の遅い経路を使う理由の数. 評価の深さの制限，デバッガ，
+// with-output-to-string による出力の束縛，関数のトレースのどれかが
+// 有効な間は正である。そのときだけ Eval は深さを数えたりエラーを
+// デバッガに渡したりし，関数の呼び出しは動的な束縛を引き継ぐ。
+var slowReasons int32
+
+// 評価の遅い経路を使うならば true を返す。
+func slowPath() bool {
+	return atomic.LoadInt32(&slowReasons) != 0
+}
+
+// 評価の遅い経路を使う理由を一つ増やす。減らす関数を返す。
+func beginSlowPath() (end func()) {
+	atomic.AddInt32(&slowReasons, 1)
+	return func() { atomic.AddInt32(&slowReasons, -1) }
+}
+
+// 評価の入れ子の深さの上限. 0 ならば制限しない。
+var maxDepth int64
+var endDepthLimit = func() {}
+
+// 評価の入れ子の深さの上限を n にする。0 ならば制限しない。
+// 評価を始める前に呼ぶこと。
+func SetMaxDepth(n int64) {
+	endDepthLimit()
+	endDepthLimit = func() {}
+	if n > 0 {
+		endDepthLimit = beginSlowPath()
+	}
+	atomic.StoreInt64(&maxDepth, n)
+}
+
+// 最初のゴルーチンで評価中の式の入れ子の深さ
+var evalDepth int64
+
+// 評価の入れ子の深さを環境に記録するための (intern されない) シンボル.
+// 深さを制限するとき，future を評価する環境にはそのゴルーチンの深さ
+// (*int64) を束縛する。
+var depthSymbol = &Symbol{string: "#<eval depth>"}
+
+// 関数の呼び出しで呼び出し元から引き継ぐ動的な束縛のシンボル
+var dynamicSymbols = []*Symbol{depthSymbol, outputSymbol, traceSymbol}
+
+// 環境で評価している式の入れ子の深さを返す。
+func (env *Env) depth() *int64 {
+	if v, ok := env.Lookup(depthSymbol); ok {
+		return v.(*int64)
+	}
+	return &evalDepth
+}
+
+// 環境 lambdaEnv で作った関数を環境 argsEnv から呼び出すための環境を作る。
+// 遅い経路では呼び出し元の動的な束縛を引き継ぐ。
+func newCallEnv(lambdaEnv, argsEnv *Env) *Env {
+	env := &Env{make(map[*Symbol]Any), lambdaEnv, sync.Mutex{}}
+	if slowPath() {
+		for _, sym := range dynamicSymbols {
+			if v, ok := argsEnv.Lookup(sym); ok {
+				env.Table[sym] = v
+			}
+		}
+	}
+	return env
+}
+
 // 与えられた環境のもとで引数を評価する。
 func (env *Env) Eval(a Any) Any {
+	if slowPath() {
+		return env.slowEval(a)
+	}
+	return env.eval(a, nil)
+}
+
+// 遅い経路の評価. 深さを制限するとき評価の入れ子がそれより深く
+// なればパニックする。深さは future のゴルーチンごとに数える。
+// デバッガが有効ならばエラーをデバッガに渡す。
+func (env *Env) slowEval(a Any) Any {
+	if limit := atomic.LoadInt64(&maxDepth); limit > 0 {
+		depth := env.depth()
+		defer atomic.AddInt64(depth, -1)
+		if atomic.AddInt64(depth, 1) > limit {
+			panic(fmt.Errorf("evaluation too deep: more than %d levels", limit))
+		}
+	}
+	if d := currentDebugger.Load(); d != nil {
+		return env.debugEval(a, d)
+	}
+	return env.eval(a, nil)
+}
+
+// 与えられた環境のもとで引数を評価する (深さを数えない)。
+// cur が nil でなければ末尾式を評価する環境を cur に知らせる。
+func (env *Env) eval(a Any, cur *debugCursor) Any {
 	for {
 		switch x := a.(type) {
 		case *Cell:
@@ -66,6 +194,9 @@ func (env *Env) Eval(a Any) Any {
 				if env == nil {
 					return a
 				}
+				if cur != nil {
+					cur.step(env)
+				}
 				// 次回のループで末尾式 a を環境 env (≠nil)で評価する。
 			case (func([]Any) Any): // 一般の関数
 				arg := make([]Any, 0, 4)
@@ -74,16 +205,25 @@ func (env *Env) Eval(a Any) Any {
 					arg = append(arg, e)
 				}
 				return fn(arg)
+			case (func([]Any, *Env) Any): // 環境から出力先などを得る関数
+				return fn(evalArgs(x.Cdr, env), env)
 			default:
 				panic(fmt.Errorf("not function: %s", StringFor(x.Car)))
 			}
 		case *Symbol:
+			if x.IsKeyword() {
+				return x
+			}
+			if x == StandardOutputSymbol && slowPath() {
+				if s := env.boundOutput(); s != nil {
+					return s
+				}
+			}
 			return env.Get(x)
 		default:
 			return x
 		}
 	}
-	panic(fmt.Errorf("never: %s", StringFor(a)))
 }
 
 /*
@@ -0,0 +1,58 @@
+// H25.5/13 (鈴)
+
+package lisp
+
+import (
+	"testing"
+)
+
+func TestMaxDepthPerFuture(t *testing.T) {
+	defer SetMaxDepth(0)
+	SetMaxDepth(400)
+	checkEval(t, []evalCase{
+		{`(defun depth-test (n) (if (= n 0) 0 (+ 1 (depth-test (- n 1)))))
+		  (setq depth-futures
+		    (list (future (depth-test 100)) (future (depth-test 100))
+		          (future (depth-test 100)) (future (depth-test 100))
+		          (future (depth-test 100)) (future (depth-test 100))
+		          (future (depth-test 100)) (future (depth-test 100))))
+		  (apply + (list (force (car depth-futures))
+		                 (force (car (cdr depth-futures)))))`, "200"},
+		{"(depth-test 1000)", "error: evaluation too deep: more than 400 levels"},
+		{"(force (future (depth-test 1000)))",
+			"error: evaluation too deep: more than 400 levels"},
+		{"(depth-test 100)", "100"},
+	})
+}
+
+func TestFutureError(t *testing.T) {
+	checkEval(t, []evalCase{
+		{"(setq bad-future (future (car 1))) (force bad-future)",
+			"error: interface conversion: lisp.Any is int32, not *lisp.Cell"},
+		{"(force bad-future)",
+			"error: interface conversion: lisp.Any is int32, not *lisp.Cell"},
+		{"(force (future (+ 1 2)))", "3"},
+	})
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
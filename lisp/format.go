@@ -0,0 +1,475 @@
+// H25.5/4 (鈴)
+
+// このファイルは Common Lisp 風の書式指定による出力 format を実装する。
+// 書式指定の文字列はまず指令の木に解析し，それを引数に適用する。
+//
+// 指令: ~a ~s ~d ~b ~o ~x ~f ~% ~& ~~ ~* ~^ ~{...~} ~[...~;...~]
+// 各指令の前には , で区切ったパラメタ (整数，'文字，v，#) と
+// 修飾子 : と @ を置ける。例: ~5,'0d ~,2f ~:[偽~;真~] ~@{~a~^, ~}
+
+package lisp
+
+import (
+	"github.com/pkelchte/tiny-lisp/arith"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// 書式指定の指令
+type fmtDirective struct {
+	char       byte              // 指令の文字 (小文字)。0 ならば text
+	text       string            // 指令でない文字列
+	params     []Any             // nil (省略), int, rune, fmtV, fmtCount
+	colon      bool              // : 修飾子
+	at         bool              // @ 修飾子
+	body       []*fmtDirective   // ~{ の本体
+	clauses    [][]*fmtDirective // ~[ の節
+	hasDefault bool              // ~[ の最後の節が ~:; で始まるか？
+}
+
+// パラメタ v (引数から値をとる) と # (残りの引数の個数)
+type fmtV struct{}
+type fmtCount struct{}
+
+// 書式指定の文字列を指令の列に解析する。
+func parseFormat(ctl string) []*fmtDirective {
+	p := &fmtParser{ctl, 0}
+	ds, end := p.parse("")
+	if end != nil {
+		panic(fmt.Errorf("format: unexpected ~%c: %q", end.char, ctl))
+	}
+	return ds
+}
+
+type fmtParser struct {
+	ctl string
+	pos int
+}
+
+// terminators のどれかの指令に出会うまで解析し，その指令も返す。
+func (p *fmtParser) parse(terminators string) ([]*fmtDirective,
+	*fmtDirective) {
+	var ds []*fmtDirective
+	for p.pos < len(p.ctl) {
+		i := strings.IndexByte(p.ctl[p.pos:], '~')
+		if i < 0 {
+			ds = append(ds, &fmtDirective{text: p.ctl[p.pos:]})
+			p.pos = len(p.ctl)
+			break
+		}
+		if i > 0 {
+			ds = append(ds, &fmtDirective{text: p.ctl[p.pos : p.pos+i]})
+		}
+		p.pos += i + 1
+		d := p.parseDirective()
+		if strings.IndexByte(terminators, d.char) >= 0 {
+			return ds, d
+		}
+		switch d.char {
+		case '{':
+			body, end := p.parse("}")
+			if end == nil {
+				panic(fmt.Errorf("format: ~} expected: %q", p.ctl))
+			}
+			d.body = body
+		case '[':
+			for {
+				clause, end := p.parse(";]")
+				if end == nil {
+					panic(fmt.Errorf("format: ~] expected: %q", p.ctl))
+				}
+				d.clauses = append(d.clauses, clause)
+				if end.char == ']' {
+					break
+				}
+				if end.colon {
+					d.hasDefault = true
+				}
+			}
+		case '}', ']', ';':
+			panic(fmt.Errorf("format: unexpected ~%c: %q", d.char, p.ctl))
+		}
+		ds = append(ds, d)
+	}
+	return ds, nil
+}
+
+// ~ に続くパラメタ，修飾子，指令の文字を解析する。
+func (p *fmtParser) parseDirective() *fmtDirective {
+	d := &fmtDirective{}
+	ctl := p.ctl
+	for {
+		start := p.pos
+		switch {
+		case p.pos >= len(ctl):
+			panic(fmt.Errorf("format: incomplete directive: %q", ctl))
+		case ctl[p.pos] == '\'' && p.pos+1 < len(ctl):
+			r := []rune(ctl[p.pos+1:])[0]
+			d.params = append(d.params, r)
+			p.pos += 1 + len(string(r))
+		case ctl[p.pos] == 'v' || ctl[p.pos] == 'V':
+			d.params = append(d.params, fmtV{})
+			p.pos++
+		case ctl[p.pos] == '#':
+			d.params = append(d.params, fmtCount{})
+			p.pos++
+		default:
+			for p.pos < len(ctl) && (ctl[p.pos] >= '0' && ctl[p.pos] <= '9' ||
+				p.pos == start && (ctl[p.pos] == '-' || ctl[p.pos] == '+')) {
+				p.pos++
+			}
+			if p.pos > start {
+				n, err := strconv.Atoi(ctl[start:p.pos])
+				if err != nil {
+					panic(fmt.Errorf("format: invalid parameter %q: %q",
+						ctl[start:p.pos], ctl))
+				}
+				d.params = append(d.params, n)
+			} else if p.pos < len(ctl) && ctl[p.pos] == ',' {
+				d.params = append(d.params, nil)
+			}
+		}
+		if p.pos < len(ctl) && ctl[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	for p.pos < len(ctl) && (ctl[p.pos] == ':' || ctl[p.pos] == '@') {
+		if ctl[p.pos] == ':' {
+			d.colon = true
+		} else {
+			d.at = true
+		}
+		p.pos++
+	}
+	if p.pos >= len(ctl) {
+		panic(fmt.Errorf("format: incomplete directive: %q", ctl))
+	}
+	c := ctl[p.pos]
+	if 'A' <= c && c <= 'Z' {
+		c += 'a' - 'A'
+	}
+	d.char = c
+	p.pos++
+	return d
+}
+
+// 書式指定を適用する状態
+type formatter struct {
+	out  strings.Builder
+	args []Any // 残りの引数
+}
+
+// 次の引数を取り出す。
+func (f *formatter) next() Any {
+	if len(f.args) == 0 {
+		panic(fmt.Errorf("format: not enough arguments"))
+	}
+	a := f.args[0]
+	f.args = f.args[1:]
+	return a
+}
+
+// i 番目のパラメタを整数として得る。省略されていれば def を返す。
+func (f *formatter) intParam(d *fmtDirective, i, def int) int {
+	switch x := f.param(d, i).(type) {
+	case int:
+		return x
+	case nil:
+		return def
+	}
+	panic(fmt.Errorf("format: integer parameter expected for ~%c", d.char))
+}
+
+// i 番目のパラメタを文字として得る。省略されていれば def を返す。
+func (f *formatter) runeParam(d *fmtDirective, i int, def rune) rune {
+	switch x := f.param(d, i).(type) {
+	case rune:
+		return x
+	case nil:
+		return def
+	}
+	panic(fmt.Errorf("format: character parameter expected for ~%c",
+		d.char))
+}
+
+// i 番目のパラメタを得る。v と # はここで値にする。
+func (f *formatter) param(d *fmtDirective, i int) Any {
+	if i >= len(d.params) {
+		return nil
+	}
+	switch d.params[i].(type) {
+	case fmtV:
+		switch x := f.next().(type) {
+		case int32:
+			return int(x)
+		case string:
+			if x == "" {
+				panic(fmt.Errorf("format: empty string for v parameter of ~%c",
+					d.char))
+			}
+			return []rune(x)[0]
+		case *Cell:
+			if x == nil {
+				return nil
+			}
+		}
+		panic(fmt.Errorf("format: bad v parameter for ~%c", d.char))
+	case fmtCount:
+		return len(f.args)
+	}
+	return d.params[i]
+}
+
+// 文字列を桁数 mincol まで padChar で埋める。left ならば左を埋める。
+func pad(s string, mincol int, padChar rune, left bool) string {
+	n := mincol - len([]rune(s))
+	if n <= 0 {
+		return s
+	}
+	padding := strings.Repeat(string(padChar), n)
+	if left {
+		return padding + s
+	}
+	return s + padding
+}
+
+// 文字列を n 回繰り返す。n が負ならば空文字列を返す。
+func repeat(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat(s, n)
+}
+
+// ~a で表示する文字列表現. 文字列は引用符を付けずにそのまま表示し，
+// 数には近似値の注釈を付けない。
+func aestheticStringFor(a Any) string {
+	if s, ok := a.(string); ok {
+		return s
+	}
+	if arith.IsNumber(a) {
+		return arith.String(a)
+	}
+	return StringFor(a)
+}
+
+// 指令の列を適用する。~^ で抜けたときは false を返す。
+func (f *formatter) run(ds []*fmtDirective) bool {
+	for _, d := range ds {
+		if !f.runDirective(d) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *formatter) runDirective(d *fmtDirective) bool {
+	switch d.char {
+	case 0:
+		f.out.WriteString(d.text)
+	case 'a', 's':
+		mincol := f.intParam(d, 0, 0)
+		a := f.next()
+		var s string
+		if d.char == 'a' {
+			s = aestheticStringFor(a)
+		} else {
+			s = StringFor(a)
+		}
+		f.out.WriteString(pad(s, mincol, ' ', d.at))
+	case 'd', 'b', 'o', 'x':
+		mincol := f.intParam(d, 0, 0)
+		padChar := f.runeParam(d, 1, ' ')
+		a := f.next()
+		var s string
+		if arith.IsInteger(a) {
+			radix := map[byte]int{'d': 10, 'b': 2, 'o': 8, 'x': 16}[d.char]
+			s = arith.FormatRadix(a, radix)
+			if d.at && arith.Sign(a) >= 0 {
+				s = "+" + s
+			}
+		} else {
+			s = aestheticStringFor(a)
+		}
+		f.out.WriteString(pad(s, mincol, padChar, true))
+	case 'f':
+		width := f.intParam(d, 0, 0)
+		digits := f.intParam(d, 1, -1)
+		a := f.next()
+		var s string
+		if arith.IsNumber(a) {
+			if digits >= 0 {
+				s = arith.FormatFixed(a, digits)
+			} else {
+				s = arith.String(arith.ToInexact(a))
+			}
+			if d.at && arith.Sign(a) >= 0 {
+				s = "+" + s
+			}
+		} else {
+			s = aestheticStringFor(a)
+		}
+		f.out.WriteString(pad(s, width, ' ', true))
+	case '%':
+		f.out.WriteString(repeat("\n", f.intParam(d, 0, 1)))
+	case '&':
+		n := f.intParam(d, 0, 1)
+		if n > 0 {
+			s := f.out.String()
+			if s != "" && !strings.HasSuffix(s, "\n") {
+				f.out.WriteString("\n")
+			}
+			f.out.WriteString(repeat("\n", n-1))
+		}
+	case '~':
+		f.out.WriteString(repeat("~", f.intParam(d, 0, 1)))
+	case '*':
+		for n := f.intParam(d, 0, 1); n > 0; n-- {
+			f.next()
+		}
+	case '^':
+		if len(f.args) == 0 {
+			return false
+		}
+	case '{':
+		f.iterate(d)
+	case '[':
+		return f.choose(d)
+	default:
+		panic(fmt.Errorf("format: unknown directive ~%c", d.char))
+	}
+	return true
+}
+
+// ~{...~} 引数のリスト (@ ならば残りの引数) の各要素に本体を繰り返す。
+// 第１パラメタは繰返しの最大回数である。
+func (f *formatter) iterate(d *fmtDirective) {
+	max := f.intParam(d, 0, -1)
+	var items []Any
+	if d.at {
+		items, f.args = f.args, nil
+	} else {
+		list, ok := f.next().(*Cell)
+		if !ok {
+			panic(fmt.Errorf("format: list expected for ~{"))
+		}
+		for ; list != nil; list = list.Cdr {
+			items = append(items, list.Car)
+		}
+	}
+	sub := &formatter{args: items}
+	for i := 0; len(sub.args) > 0 && i != max; i++ {
+		n := len(sub.args)
+		ok := sub.run(d.body)
+		if !ok || len(sub.args) == n { // ~^ で抜けたか引数を消費しない
+			break
+		}
+	}
+	f.out.WriteString(sub.out.String())
+}
+
+// ~[...~;...~] 引数 (またはパラメタ) の整数で節を選ぶ。
+// ~:[偽~;真~] は引数の真偽で，~@[...~] は引数が真のときだけ
+// (引数を消費せずに) 節を選ぶ。節の中の ~^ で抜けたならば false を返す。
+func (f *formatter) choose(d *fmtDirective) bool {
+	var clause []*fmtDirective
+	switch {
+	case d.colon:
+		if len(d.clauses) != 2 {
+			panic(fmt.Errorf("format: ~:[ needs two clauses"))
+		}
+		if f.next() == (*Cell)(nil) {
+			clause = d.clauses[0]
+		} else {
+			clause = d.clauses[1]
+		}
+	case d.at:
+		if len(f.args) == 0 {
+			panic(fmt.Errorf("format: not enough arguments"))
+		}
+		if f.args[0] == (*Cell)(nil) {
+			f.args = f.args[1:]
+			return true
+		}
+		clause = d.clauses[0]
+	default:
+		var n int
+		if len(d.params) > 0 {
+			n = f.intParam(d, 0, 0)
+		} else {
+			i, ok := f.next().(int32)
+			if !ok {
+				panic(fmt.Errorf("format: integer expected for ~["))
+			}
+			n = int(i)
+		}
+		if 0 <= n && n < len(d.clauses) &&
+			!(d.hasDefault && n == len(d.clauses)-1) {
+			clause = d.clauses[n]
+		} else if d.hasDefault {
+			clause = d.clauses[len(d.clauses)-1]
+		}
+	}
+	return f.run(clause)
+}
+
+// 書式指定 ctl を引数 args に適用した文字列を返す。
+func Format(ctl string, args []Any) string {
+	f := &formatter{args: args}
+	f.run(parseFormat(ctl))
+	return f.out.String()
+}
+
+// (format destination control-string arg...)
+// destination が t ならば *standard-output* へ，nil ならば文字列として返し，
+// io.Writer (ストリーム) ならばそれへ出力する。
+func formatFunc(a []Any, env *Env) Any {
+	CheckArity(-2, a)
+	ctl, ok := a[1].(string)
+	if !ok {
+		panic(fmt.Errorf("format: control string expected: %s",
+			StringFor(a[1])))
+	}
+	s := Format(ctl, a[2:])
+	switch dest := a[0].(type) {
+	case *Cell:
+		if dest == nil {
+			return s
+		}
+	case *Symbol:
+		if dest == TSymbol {
+			io.WriteString(standardOutput(env), s)
+			return (*Cell)(nil)
+		}
+	case io.Writer:
+		io.WriteString(dest, s)
+		return (*Cell)(nil)
+	}
+	panic(fmt.Errorf("format: bad destination: %s", StringFor(a[0])))
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
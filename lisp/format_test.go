@@ -0,0 +1,61 @@
+// H25.5/13 (鈴)
+
+package lisp
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	checkEval(t, []evalCase{
+		{`(format nil "~a ~s" "x" "x")`, `"x \"x\""`},
+		{`(format nil "~a|~s" 1/2 1/2)`, `"1/2|1/2 /*=0.5*/"`},
+		{`(format nil "~5a|~5@a|" "ab" "cd")`, `"ab   |   cd|"`},
+		{`(format nil "~5,'0d|~x|~b|~o|~@d" 42 255 5 8 3)`,
+			`"00042|ff|101|10|+3"`},
+		{`(format nil "~d" 100000000000000000000)`, `"100000000000000000000"`},
+		{`(format nil "~d" "x")`, `"x"`},
+		{`(format nil "~,2f|~8,3f|~@f" 3.14159 2 1/2)`, `"3.14|   2.000|+0.5"`},
+		{`(format nil "a~%b~&~&c~~")`, `"a\nb\nc~"`},
+		{`(format nil "~a ~* ~a" 1 2 3)`, `"1  3"`},
+		{`(format nil "~{~a~^, ~}" '(1 2 3))`, `"1, 2, 3"`},
+		{`(format nil "~@{~a~^-~}" 1 2 3)`, `"1-2-3"`},
+		{`(format nil "~:[no~;yes~]|~[a~;b~;c~]|~[a~:;z~]" t 1 9)`, `"yes|b|z"`},
+		{`(format nil "~#[none~;one~;two~]" 1 2)`, `"two"`},
+		{`(format nil "~vd|~v,vd" 4 7 4 "*" 7)`, `"   7|***7"`},
+		{`(format nil "~vd" "" 5)`,
+			"error: format: empty string for v parameter of ~d"},
+		{`(format nil "~{~a~:[~;~^~], ~}" '(1 t 2 t))`, `"1, 2"`},
+		{`(format nil "~@{~a~:[~;~^~], ~}" 1 () 2 t)`, `"1, 2"`},
+		{`(format nil "~a~[~^~]." 1 0)`, `"1"`},
+		{`(format nil "~+d" 1)`, `error: format: invalid parameter "+": "~+d"`},
+		{`(format nil "~5,-a" 1)`,
+			`error: format: invalid parameter "-": "~5,-a"`},
+		{`(format nil "~-3a|" 1)`, `"1|"`},
+		{`(format nil "~a")`, "error: format: not enough arguments"},
+		{`(format nil "~{~a")`, `error: format: ~} expected: "~{~a"`},
+		{`(format nil "~z" 1)`, "error: format: unknown directive ~z"},
+	})
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -7,15 +7,24 @@ package lisp
 import (
 	"github.com/pkelchte/tiny-lisp/arith"
 	"fmt"
+	"os"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // トップレベルの環境
-var Globals = &Env{map[*Symbol]Any{
+var Globals = &Env{make(map[*Symbol]Any), nil, sync.Mutex{}}
+
+// トップレベルの環境にあらかじめ束縛する組込みの関数と変数.
+// 関数から Globals を参照できるように Globals とは別に用意し，
+// init で Globals に登録する。
+var builtins = map[*Symbol]Any{
 	TSymbol:          TSymbol,
 	NewSymbol("car"): carFunc, NewSymbol("cdr"): cdrFunc,
 	NewSymbol("cons"):  consFunc,
 	NewSymbol("listp"): listpFunc, NewSymbol("eq"): eqFunc,
+	NewSymbol("equal"):  equalFunc,
 	NewSymbol("rplaca"): rplacaFunc, NewSymbol("rplacd"): rplacdFunc,
 	NewSymbol("list"): listFunc,
 	NewSymbol("="):    eqOp, NewSymbol("/="): neOp,
@@ -23,16 +32,94 @@ var Globals = &Env{map[*Symbol]Any{
 	NewSymbol(">"): gtOp, NewSymbol(">="): geOp,
 	NewSymbol("+"): addOp, NewSymbol("-"): subtractOp,
 	NewSymbol("*"): multiplyOp, NewSymbol("/"): divideOp,
-	NewSymbol("gensym"): gensymFunc,
-	NewSymbol("print"):  printFunc,
-	QuoteSymbol:         quoteForm,
-	NewSymbol("setq"):   setqForm,
-	NewSymbol("progn"):  prognForm, NewSymbol("if"): ifForm,
+	NewSymbol("quotient"): quotientOp, NewSymbol("remainder"): remainderOp,
+	NewSymbol("mod"):      modOp,
+	NewSymbol("truncate"): truncateOp, NewSymbol("floor"): floorOp,
+	NewSymbol("ceiling"): ceilingOp, NewSymbol("round"): roundOp,
+	NewSymbol("abs"): absOp, NewSymbol("min"): minOp, NewSymbol("max"): maxOp,
+	NewSymbol("gcd"): gcdOp, NewSymbol("lcm"): lcmOp,
+	NewSymbol("expt"):      exptOp,
+	NewSymbol("numerator"): numeratorOp, NewSymbol("denominator"): denominatorOp,
+	NewSymbol("exact->inexact"): exactToInexactOp,
+	NewSymbol("inexact->exact"): inexactToExactOp,
+	NewSymbol("integerp"):       integerpFunc, NewSymbol("rationalp"): rationalpFunc,
+	NewSymbol("floatp"): floatpFunc, NewSymbol("numberp"): numberpFunc,
+	NewSymbol("pi"): arith.Pi, NewSymbol("e"): arith.E,
+	NewSymbol("sqrt"): sqrtOp, NewSymbol("exp"): expOp, NewSymbol("log"): logOp,
+	NewSymbol("sin"): sinOp, NewSymbol("cos"): cosOp, NewSymbol("tan"): tanOp,
+	NewSymbol("asin"): asinOp, NewSymbol("acos"): acosOp,
+	NewSymbol("atan"): atanOp,
+	NewSymbol("sinh"): sinhOp, NewSymbol("cosh"): coshOp,
+	NewSymbol("tanh"):   tanhOp,
+	NewSymbol("logand"): logandOp, NewSymbol("logior"): logiorOp,
+	NewSymbol("logxor"): logxorOp, NewSymbol("lognot"): lognotOp,
+	NewSymbol("ash"): ashOp, NewSymbol("integer-length"): integerLengthOp,
+	NewSymbol("bit-count"): bitCountOp, NewSymbol("isqrt"): isqrtOp,
+	NewSymbol("expt-mod"): exptModOp, NewSymbol("prime-p"): primepFunc,
+	NewSymbol("random"):            randomFunc,
+	NewSymbol("make-random-state"): makeRandomStateFunc,
+	NewSymbol("gensym"):            gensymFunc,
+	NewSymbol("print"):             printFunc,
+	NewSymbol("pprint"):            pprintFunc,
+	NewSymbol("format"):            formatFunc,
+	StandardOutputSymbol:           NewOutputStream("stdout", os.Stdout),
+	StandardInputSymbol:            stdinStream,
+	NewSymbol("open-input-file"):   openInputFileFunc,
+	NewSymbol("open-output-file"):  openOutputFileFunc,
+	NewSymbol("close"):             closeFunc,
+	NewSymbol("with-open-file"):    withOpenFileForm,
+	NewSymbol("read-line"):         readLineFunc, NewSymbol("read-char"): readCharFunc,
+	NewSymbol("write-string"): writeStringFunc, NewSymbol("write"): writeFunc,
+	NewSymbol("display"): displayFunc, NewSymbol("newline"): newlineFunc,
+	NewSymbol("with-output-to-string"): withOutputToStringForm,
+	NewSymbol("read"):                  readFunc, NewSymbol("read-from-string"): readFromStringFunc,
+	NewSymbol("eval"): evalFunc, NewSymbol("the-environment"): theEnvironmentForm,
+	NewSymbol("load"):    loadFunc,
+	NewSymbol("require"): requireFunc, NewSymbol("provide"): provideFunc,
+	NewSymbol("module"):     moduleForm,
+	NewSymbol("defpackage"): defpackageForm,
+	NewSymbol("in-package"): inPackageForm,
+	NewSymbol("export"):     exportFunc, NewSymbol("find-package"): findPackageFunc,
+	NewSymbol("intern"): internFunc, NewSymbol("symbol-name"): symbolNameFunc,
+	NewSymbol("symbol-package"): symbolPackageFunc,
+	NewSymbol("package-name"):   packageNameFunc,
+	PackageSymbol:               UserPackage,
+	NewSymbol("get"):            getFunc, NewSymbol("put"): putFunc,
+	NewSymbol("remprop"): rempropFunc, NewSymbol("symbol-plist"): symbolPlistFunc,
+	NewSymbol("symbol-value"):    symbolValueFunc,
+	NewSymbol("symbol-function"): symbolFunctionFunc,
+	NewSymbol("boundp"):          boundpFunc, NewSymbol("fboundp"): fboundpFunc,
+	NewSymbol("makunbound"):        makunboundFunc,
+	NewSymbol("documentation"):     documentationFunc,
+	NewSymbol("set-documentation"): setDocumentationFunc,
+	NewSymbol("describe"):          describeFunc, NewSymbol("apropos"): aproposFunc,
+	ModulesSymbol: (*Cell)(nil), LoadPathnameSymbol: (*Cell)(nil),
+	ArgvSymbol:         (*Cell)(nil),
+	NewSymbol("trace"): traceForm, NewSymbol("untrace"): untraceForm,
+	TraceOutputSymbol: (*Cell)(nil),
+	NewSymbol("exit"): exitFunc, NewSymbol("getenv"): getenvFunc,
+	PrintCircleSymbol: (*Cell)(nil),
+	resultSymbols[0]:  (*Cell)(nil), resultSymbols[1]: (*Cell)(nil),
+	inputSymbols[0]: (*Cell)(nil), inputSymbols[1]: (*Cell)(nil),
+	PrintPrettySymbol: (*Cell)(nil), PrintRightMarginSymbol: int32(DefaultRightMargin),
+	QuoteSymbol:        quoteForm,
+	NewSymbol("setq"):  setqForm,
+	NewSymbol("progn"): prognForm, NewSymbol("if"): ifForm,
 	NewSymbol("lambda"): lambdaForm, NewSymbol("let"): letForm,
 	NewSymbol("defun"): defunForm,
 	NewSymbol("apply"): applyForm, NewSymbol("and"): andForm,
 	NewSymbol("future"): futureForm, NewSymbol("force"): forceFunc,
-}, nil, sync.Mutex{}}
+	NewSymbol("defstruct"):      defstructForm,
+	PrintApproximationSymbol:    TSymbol,
+	NewSymbol("number->string"): numberToStringFunc,
+}
+
+func init() {
+	for sym, val := range builtins {
+		Globals.Table[sym] = val
+	}
+	Globals.Table[LoadPathSymbol] = initialLoadPath()
+}
 
 // 一般の関数
 
@@ -62,6 +149,11 @@ func eqFunc(a []Any) Any {
 	return LispBool(a[0] == a[1])
 }
 
+func equalFunc(a []Any) Any {
+	CheckArity(2, a)
+	return LispBool(Equal(a[0], a[1]))
+}
+
 func rplacaFunc(a []Any) Any {
 	CheckArity(2, a)
 	x := a[0].(*Cell)
@@ -87,33 +179,37 @@ func listFunc(a []Any) Any {
 }
 
 func eqOp(a []Any) Any {
-	CheckArity(2, a)
-	return LispBool(arith.Compare(a[0], a[1]) == 0)
+	return compareOp(a, func(c int) bool { return c == 0 })
 }
 
 func neOp(a []Any) Any {
-	CheckArity(2, a)
-	return LispBool(arith.Compare(a[0], a[1]) != 0)
+	return LispBool(compareOp(a, func(c int) bool { return c == 0 }) ==
+		(*Cell)(nil))
 }
 
 func ltOp(a []Any) Any {
-	CheckArity(2, a)
-	return LispBool(arith.Compare(a[0], a[1]) < 0)
+	return compareOp(a, func(c int) bool { return c < 0 })
 }
 
 func leOp(a []Any) Any {
-	CheckArity(2, a)
-	return LispBool(arith.Compare(a[0], a[1]) <= 0)
+	return compareOp(a, func(c int) bool { return c <= 0 })
 }
 
 func gtOp(a []Any) Any {
-	CheckArity(2, a)
-	return LispBool(arith.Compare(a[0], a[1]) > 0)
+	return compareOp(a, func(c int) bool { return c > 0 })
 }
 
 func geOp(a []Any) Any {
+	return compareOp(a, func(c int) bool { return c >= 0 })
+}
+
+// 数の比較で共通の処理. NaN との比較は常に偽とする。
+func compareOp(a []Any, test func(int) bool) Any {
 	CheckArity(2, a)
-	return LispBool(arith.Compare(a[0], a[1]) >= 0)
+	if arith.IsNaN(a[0]) || arith.IsNaN(a[1]) {
+		return LispBool(false)
+	}
+	return LispBool(test(arith.Compare(a[0], a[1])))
 }
 
 func addOp(a []Any) Any {
@@ -139,6 +235,284 @@ func divideOp(a []Any) Any {
 	return inject(a[0], a[1:], arith.DivideReal)
 }
 
+func quotientOp(a []Any) Any {
+	CheckArity(2, a)
+	return arith.DivideInt(a[0], a[1])
+}
+
+func remainderOp(a []Any) Any {
+	CheckArity(2, a)
+	return arith.Remainder(a[0], a[1])
+}
+
+func modOp(a []Any) Any {
+	CheckArity(2, a)
+	return arith.Modulo(a[0], a[1])
+}
+
+// (truncate number [divisor]) など丸めの関数で共通の処理
+func rounding(a []Any, round func(arith.Number) arith.Number) Any {
+	CheckArity(-1, a)
+	if len(a) == 1 {
+		return round(a[0])
+	}
+	CheckArity(2, a)
+	return round(arith.DivideReal(a[0], a[1]))
+}
+
+func truncateOp(a []Any) Any {
+	return rounding(a, arith.Truncate)
+}
+
+func floorOp(a []Any) Any {
+	return rounding(a, arith.FloorNumber)
+}
+
+func ceilingOp(a []Any) Any {
+	return rounding(a, arith.CeilingNumber)
+}
+
+func roundOp(a []Any) Any {
+	return rounding(a, arith.RoundNumber)
+}
+
+func absOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.Abs(a[0])
+}
+
+func minOp(a []Any) Any {
+	CheckArity(-1, a)
+	return inject(arith.Min(a[0], a[0]), a[1:], arith.Min)
+}
+
+func maxOp(a []Any) Any {
+	CheckArity(-1, a)
+	return inject(arith.Max(a[0], a[0]), a[1:], arith.Max)
+}
+
+func gcdOp(a []Any) Any {
+	return inject(0, a, arith.Gcd)
+}
+
+func lcmOp(a []Any) Any {
+	return inject(1, a, arith.Lcm)
+}
+
+func exptOp(a []Any) Any {
+	CheckArity(2, a)
+	return arith.Expt(a[0], a[1])
+}
+
+func numeratorOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.Numerator(a[0])
+}
+
+func denominatorOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.Denominator(a[0])
+}
+
+func exactToInexactOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.ToInexact(a[0])
+}
+
+func inexactToExactOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.ToExact(a[0])
+}
+
+func integerpFunc(a []Any) Any {
+	CheckArity(1, a)
+	return LispBool(arith.IsInteger(a[0]))
+}
+
+func rationalpFunc(a []Any) Any {
+	CheckArity(1, a)
+	return LispBool(arith.IsRational(a[0]))
+}
+
+func floatpFunc(a []Any) Any {
+	CheckArity(1, a)
+	return LispBool(arith.IsFloat(a[0]))
+}
+
+func numberpFunc(a []Any) Any {
+	CheckArity(1, a)
+	return LispBool(arith.IsNumber(a[0]))
+}
+
+func sqrtOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.Sqrt(a[0])
+}
+
+func expOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.Exp(a[0])
+}
+
+// (log number [base])
+func logOp(a []Any) Any {
+	CheckArity(-1, a)
+	if len(a) == 1 {
+		return arith.Log(a[0])
+	}
+	CheckArity(2, a)
+	return arith.LogBase(a[0], a[1])
+}
+
+func sinOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.Sin(a[0])
+}
+
+func cosOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.Cos(a[0])
+}
+
+func tanOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.Tan(a[0])
+}
+
+func asinOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.Asin(a[0])
+}
+
+func acosOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.Acos(a[0])
+}
+
+// (atan y [x])
+func atanOp(a []Any) Any {
+	CheckArity(-1, a)
+	if len(a) == 1 {
+		return arith.Atan(a[0])
+	}
+	CheckArity(2, a)
+	return arith.Atan2(a[0], a[1])
+}
+
+func sinhOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.Sinh(a[0])
+}
+
+func coshOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.Cosh(a[0])
+}
+
+func tanhOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.Tanh(a[0])
+}
+
+func logandOp(a []Any) Any {
+	return inject(-1, a, arith.LogAnd)
+}
+
+func logiorOp(a []Any) Any {
+	return inject(0, a, arith.LogIor)
+}
+
+func logxorOp(a []Any) Any {
+	return inject(0, a, arith.LogXor)
+}
+
+func lognotOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.LogNot(a[0])
+}
+
+func ashOp(a []Any) Any {
+	CheckArity(2, a)
+	return arith.Ash(a[0], a[1])
+}
+
+func integerLengthOp(a []Any) Any {
+	CheckArity(1, a)
+	return int32(arith.IntegerLength(a[0]))
+}
+
+func bitCountOp(a []Any) Any {
+	CheckArity(1, a)
+	return int32(arith.BitCount(a[0]))
+}
+
+func isqrtOp(a []Any) Any {
+	CheckArity(1, a)
+	return arith.Isqrt(a[0])
+}
+
+func exptModOp(a []Any) Any {
+	CheckArity(3, a)
+	return arith.ExptMod(a[0], a[1], a[2])
+}
+
+func primepFunc(a []Any) Any {
+	CheckArity(1, a)
+	return LispBool(arith.IsPrime(a[0]))
+}
+
+// (random limit [state])
+func randomFunc(a []Any) Any {
+	CheckArity(-1, a)
+	if len(a) == 1 {
+		return arith.Random(a[0])
+	}
+	CheckArity(2, a)
+	st, ok := a[1].(*arith.RandomState)
+	if !ok {
+		panic(fmt.Errorf("random-state expected: %s", StringFor(a[1])))
+	}
+	return st.Random(a[0])
+}
+
+// (make-random-state [seed]) 種を省略すると時刻から作る。
+func makeRandomStateFunc(a []Any) Any {
+	if len(a) == 0 {
+		return arith.NewRandomState(time.Now().UnixNano())
+	}
+	CheckArity(1, a)
+	return arith.NewRandomState(int64(intArg(a[0])))
+}
+
+// (number->string number [radix | :decimal [max-digits] |
+// :fixed digits | :scientific digits])
+func numberToStringFunc(a []Any) Any {
+	CheckArity(-1, a)
+	n := a[0]
+	if !arith.IsNumber(n) {
+		panic(fmt.Errorf("number expected: %s", StringFor(n)))
+	}
+	if len(a) == 1 {
+		return arith.String(n)
+	}
+	switch a[1] {
+	case Keyword("decimal"):
+		if len(a) == 2 {
+			return arith.FormatDecimal(n, MaxDecimalDigits)
+		}
+		CheckArity(3, a)
+		return arith.FormatDecimal(n, intArg(a[2]))
+	case Keyword("fixed"):
+		CheckArity(3, a)
+		return arith.FormatFixed(n, intArg(a[2]))
+	case Keyword("scientific"):
+		CheckArity(3, a)
+		return arith.FormatScientific(n, intArg(a[2]))
+	}
+	CheckArity(2, a)
+	return arith.FormatRadix(n, intArg(a[1]))
+}
+
 var gensymCount = 0
 
 func gensymFunc(a []Any) Any {
@@ -147,9 +521,15 @@ func gensymFunc(a []Any) Any {
 	return NewSymbol(fmt.Sprintf("G%05d", gensymCount))
 }
 
-func printFunc(a []Any) Any {
+func printFunc(a []Any, env *Env) Any {
 	CheckArity(1, a)
-	fmt.Println(StringFor(a[0]))
+	fmt.Fprintln(standardOutput(env), StringFor(a[0]))
+	return a[0]
+}
+
+func pprintFunc(a []Any, env *Env) Any {
+	CheckArity(1, a)
+	fmt.Fprintln(standardOutput(env), PrettyStringFor(a[0], RightMargin()))
 	return a[0]
 }
 
@@ -190,17 +570,36 @@ func ifForm(x *Cell, env *Env) (Any, *Env) {
 	return prognForm(c, env)
 }
 
-// (lambda ([variable...]) expression...)
+// (lambda (lambda-list...) [documentation] expression...)
+// lambda-list については args.go の LambdaList を見よ。
+// 本体の先頭の文字列は説明文字列であり評価しない。
 func lambdaForm(x *Cell, lambdaEnv *Env) (Any, *Env) {
+	return makeLambda(x, lambdaEnv, nil), nil
+}
+
+// lambda 式から関数を作る。name は defun した関数の名前または nil である。
+func makeLambda(x *Cell, lambdaEnv *Env, name *Symbol) func(*Cell, *Env) (Any, *Env) {
 	a, b := CheckForUnaryAndRest(x)
+	_, b = splitDocString(b)
 	params, ok := a.(*Cell)
 	if !ok {
-		panic(fmt.Errorf("parameter list expected: %s", a))
+		panic(fmt.Errorf("parameter list expected: %s", StringFor(a)))
+	}
+	ll := ParseLambdaList(params)
+	if name != nil {
+		name.setLambdaList(ll)
+		if fn := profiledBody(name, ll, b, lambdaEnv); fn != nil {
+			return fn
+		}
+		if fn := debuggedBody(name, ll, b, lambdaEnv); fn != nil {
+			return fn
+		}
 	}
 	return func(args *Cell, argsEnv *Env) (Any, *Env) {
-		table := makeArgTable(params, args, argsEnv)
-		return prognForm(b, &Env{table, lambdaEnv, sync.Mutex{}})
-	}, nil
+		env := newCallEnv(lambdaEnv, argsEnv)
+		ll.BindArgs(args, argsEnv, env)
+		return prognForm(b, env)
+	}
 }
 
 // (let ([var|(var expression)...]) expression...)
@@ -222,12 +621,23 @@ func letForm(x *Cell, env *Env) (Any, *Env) {
 	return prognForm(b, &Env{table, env, sync.Mutex{}})
 }
 
-// (defun name ([variable...]) expession...)
+// (defun name (lambda-list...) [documentation] expession...)
+// 説明文字列は name に付ける。
 func defunForm(x *Cell, env *Env) (Any, *Env) {
 	a, b := CheckForUnaryAndRest(x)
 	sym := a.(*Symbol)
-	lambda, _ := lambdaForm(b, env)
-	env.Set(sym, lambda)
+	if b != nil {
+		if doc, _ := splitDocString(b.Cdr); doc != "" {
+			sym.SetDocumentation(doc)
+		}
+	}
+	lambda := makeLambda(b, env, sym)
+	forgetTrace(sym)
+	if moduleOf(env) != nil { // モジュールの中では外側の同名の関数を隠す
+		env.define(sym, lambda)
+	} else {
+		env.Set(sym, lambda)
+	}
 	return sym, nil
 }
 
@@ -267,9 +677,35 @@ type Future struct {
 	Lock   sync.Mutex
 }
 
+// future の文字列表現 (表示用)
+func (fu *Future) String() string {
+	return "#<future>"
+}
+
+// 式 a を環境 env で評価して結果を ch に送る。評価中のパニックは
+// futureError に包んで送り，force したときに改めてパニックさせる。
+// with-output-to-string による出力先の束縛は future の評価に及ぼさない。
 func futureTask(a Any, env *Env, ch chan<- Any) {
-	ch <- env.Eval(a)
-	close(ch)
+	defer close(ch)
+	defer func() {
+		if r := recover(); r != nil {
+			ch <- &futureError{r}
+		}
+	}()
+	if slowPath() {
+		table := map[*Symbol]Any{outputSymbol: (*Stream)(nil),
+			traceSymbol: newFutureTraceLevel()}
+		if atomic.LoadInt64(&maxDepth) > 0 { // 評価の深さをこのゴルーチンで数え直す。
+			table[depthSymbol] = new(int64)
+		}
+		env = &Env{table, env, sync.Mutex{}}
+	}
+	ch <- evalFuture(a, env)
+}
+
+// future の評価中のパニック
+type futureError struct {
+	err Any
 }
 
 // (force expession)
@@ -282,6 +718,9 @@ func forceFunc(a []Any) Any {
 			fu.Ch = nil
 		}
 		fu.Lock.Unlock()
+		if e, ok := fu.Result.(*futureError); ok {
+			panic(e.err)
+		}
 		return fu.Result
 	}
 	return a[0]
@@ -301,6 +740,14 @@ func CheckArity(arity int, a []Any) {
 	}
 }
 
+// 引数が int32 の整数か確かめて int として返す。
+func intArg(a Any) int {
+	if i, ok := a.(int32); ok {
+		return int(i)
+	}
+	panic(fmt.Errorf("small integer expected: %s", StringFor(a)))
+}
+
 // 真ならばシンボル t を，偽ならば空リストを返す。
 func LispBool(t bool) Any {
 	if t {
@@ -365,36 +812,22 @@ func CheckForBinaryAndRest(x *Cell) (Any, Any, *Cell) {
 	panic(fmt.Errorf("arity 2+; given %s", StringFor(x)))
 }
 
-// 仮引数名から実引数値への対応表を作る。
-func makeArgTable(params *Cell, args *Cell, env *Env) map[*Symbol]Any {
-	table := make(map[*Symbol]Any)
-	for ; params != nil; params = params.Cdr {
-		sym := params.Car.(*Symbol)
-		if sym == AmpRestSymbol {
-			sym = CheckForUnary(params.Cdr).(*Symbol)
-			table[sym] = evlis(args, env)
-			return table
-		}
-		if args == nil {
-			panic(fmt.Errorf("missing argument for: %s", StringFor(sym)))
-		}
-		val := env.Eval(args.Car)
-		table[sym] = val
-		args = args.Cdr
+// 実引数を評価したスライスを作る。
+func evalArgs(args *Cell, env *Env) []Any {
+	arg := make([]Any, 0, 4)
+	for ; args != nil; args = args.Cdr {
+		arg = append(arg, env.Eval(args.Car))
 	}
-	if args != nil {
-		panic(fmt.Errorf("unused rest: %s", StringFor(args)))
-	}
-	return table
+	return arg
 }
 
-// 剰余引数を評価したリストを作る。
-func evlis(args *Cell, env *Env) *Cell {
-	if args == nil {
-		return nil
+// リストの要素の個数を返す。
+func listLength(x *Cell) int {
+	n := 0
+	for ; x != nil; x = x.Cdr {
+		n++
 	}
-	val := env.Eval(args.Car)
-	return Cons(val, evlis(args.Cdr, env))
+	return n
 }
 
 // 各要素をクォートしたリストを作る。(apply での二重評価を避けるため)
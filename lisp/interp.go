@@ -5,6 +5,7 @@
 package lisp
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"os"
@@ -12,6 +13,18 @@ import (
 	"text/scanner"
 )
 
+// スクリプトに与えるコマンド行引数 (文字列のリスト) の変数
+var ArgvSymbol = NewSymbol("*argv*")
+
+// コマンド行引数を変数 *argv* にセットする。
+func SetArgs(args []string) {
+	var list *Cell
+	for i := len(args) - 1; i >= 0; i-- {
+		list = Cons(args[i], list)
+	}
+	Globals.Set(ArgvSymbol, list)
+}
+
 // 文字列を読み込み式を評価する。
 // ただし，読み込んだ式が不完全ならば false を返して終わる。
 func ReadAndEval(line string) bool {
@@ -27,29 +40,75 @@ func ReadAndEval(line string) bool {
 	return true
 }
 
-// ファイルを読み込み式を評価する。
+// ファイルを読み込み式を評価する。読み込みの間は変数 *load-pathname* の
+// 値をファイル名とする。
 // ただし，読み込んだ式が不完全ならば false を返して終わる。
 func ReadAndEvalFile(fileName string) bool {
 	file, err := os.Open(fileName)
 	if err != nil {
 		panic(err)
-	} else {
-		defer func() {
-			file.Close()
-		}()
-		lex := NewLex(file)
-		for lex.Token != scanner.EOF {
-			x := lex.Read()
-			if x == nil {
-				return false
-			}
-			Globals.Eval(x)
+	}
+	defer file.Close()
+	return ReadAndEvalScript(fileName, file)
+}
+
+// スクリプトを読み込み式を評価する。先頭の #! で始まる行は読み飛ばす。
+// name が空でなければ，読み込みの間は変数 *load-pathname* の値を name と
+// し，読み込みの後に変数 *package* の値を元に戻す (ファイルの中の
+// in-package はそのファイルだけに及ぶ)。
+// ただし，読み込んだ式が不完全ならば false を返して終わる。
+func ReadAndEvalScript(name string, src io.Reader) bool {
+	if name != "" {
+		old, _ := Globals.Lookup(LoadPathnameSymbol)
+		Globals.Set(LoadPathnameSymbol, name)
+		defer Globals.Set(LoadPathnameSymbol, old)
+		p, _ := Globals.Lookup(PackageSymbol)
+		defer Globals.Set(PackageSymbol, p)
+	}
+	lex := NewLex(skipShebang(src))
+	for lex.Token != scanner.EOF {
+		x := lex.Read()
+		if x == nil {
+			return false
 		}
+		Globals.Eval(x)
 	}
 	return true
 }
 
+// 標準入力からスクリプトを読み込み式を評価する。先頭の #! で始まる
+// 行は読み飛ばす。式を一つずつ読むから，スクリプトの式は続く入力を
+// *standard-input* から読める。式が不完全ならばパニックする。
+func ReadAndEvalStdin() {
+	if b, _ := stdinStream.reader.Peek(2); string(b) == "#!" {
+		stdinStream.ReadLine()
+	}
+	for {
+		x, ok := stdinStream.Read()
+		if !ok {
+			return
+		}
+		Globals.Eval(x)
+	}
+}
+
+// 先頭の #! で始まる行を読み飛ばした入力を返す。
+// 行番号を保つため，その行の改行は残す。
+func skipShebang(src io.Reader) io.Reader {
+	r := bufio.NewReader(src)
+	if b, _ := r.Peek(2); string(b) == "#!" {
+		if _, err := r.ReadString('\n'); err == nil {
+			return io.MultiReader(strings.NewReader("\n"), r)
+		}
+	}
+	return r
+}
+
 // 入力を読み込み式を評価し結果を 元の式 => 結果の値 という形式で出力する。
+// 変数 *print-pretty* が真ならば結果をプリティプリントし，複数行になる
+// ときは => の次の行から出力する。
+// 式が * または + だけならば直前の結果または入力の式を値とし，式と結果を
+// 履歴に加える (repl.go 参照)。
 // ただし，読み込んだ式が不完全ならば false を返して終わる。
 func ReadEvalPrint(input io.Reader, output io.Writer) bool {
 	lex := NewLex(input)
@@ -59,12 +118,82 @@ func ReadEvalPrint(input io.Reader, output io.Writer) bool {
 			return false
 		}
 		fmt.Fprintf(output, "%v => ", StringFor(x))
-		y := Globals.Eval(x)
-		fmt.Fprintf(output, "%v\n", StringFor(y))
+		y := Globals.Eval(substituteHistory(x))
+		recordHistory(x, y)
+		if PrintPretty() {
+			s := PrettyStringFor(y, RightMargin())
+			if strings.Contains(s, "\n") {
+				s = "\n" + s
+			}
+			fmt.Fprintf(output, "%v\n", s)
+		} else {
+			fmt.Fprintf(output, "%v\n", StringFor(y))
+		}
 	}
 	return true
 }
 
+// (read [stream-or-string [eof-error-p [eof-value]]])
+// ストリーム (省略時は *standard-input*) または文字列から式を一つ読む。
+// EOF のとき eof-error-p が nil ならば eof-value を返し，さもなくば
+// パニックする。
+func readFunc(a []Any) Any {
+	if len(a) > 3 {
+		CheckArity(3, a)
+	}
+	var s *Stream
+	if len(a) == 0 {
+		s = streamVariable(StandardInputSymbol)
+	} else {
+		switch x := a[0].(type) {
+		case *Stream:
+			s = x
+		case string:
+			s = NewInputStream("string", strings.NewReader(x))
+		default:
+			panic(fmt.Errorf("stream or string expected: %s", StringFor(x)))
+		}
+	}
+	if x, ok := s.Read(); ok {
+		return x
+	}
+	if len(a) < 2 || a[1] != (*Cell)(nil) {
+		panic(fmt.Errorf("end of file: %s", s.Name))
+	}
+	if len(a) == 3 {
+		return a[2]
+	}
+	return (*Cell)(nil)
+}
+
+// (read-from-string string [eof-error-p [eof-value]])
+func readFromStringFunc(a []Any) Any {
+	CheckArity(-1, a)
+	if _, ok := a[0].(string); !ok {
+		panic(fmt.Errorf("string expected: %s", StringFor(a[0])))
+	}
+	return readFunc(a)
+}
+
+// (eval expression [environment])
+// 環境を省略するとトップレベルの環境で評価する。
+func evalFunc(a []Any) Any {
+	CheckArity(-1, a)
+	if len(a) == 1 {
+		return Globals.Eval(a[0])
+	}
+	CheckArity(2, a)
+	return a[1].(*Env).Eval(a[0])
+}
+
+// (the-environment) 現在の環境を返す。
+func theEnvironmentForm(x *Cell, env *Env) (Any, *Env) {
+	if x != nil {
+		panic(fmt.Errorf("arity 0; given %d", listLength(x)))
+	}
+	return env, nil
+}
+
 /*
   Copyright (c) 2013 OKI Software Co., Ltd.
 
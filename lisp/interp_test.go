@@ -0,0 +1,55 @@
+// H25.5/13 (鈴)
+
+package lisp
+
+import (
+	"testing"
+)
+
+// 評価する式と期待する値の文字列表現
+type evalCase struct {
+	src, want string
+}
+
+// 文字列の式を順に大域環境で評価し，最後の値の文字列表現を返す。
+// パニックすれば "error: " に続けてエラーを返す。
+func evalText(src string) (result string) {
+	defer func() {
+		if e := recover(); e != nil {
+			result = "error: " + errorString(e)
+		}
+	}()
+	return StringFor(evalString(src, Globals))
+}
+
+// 式を順に評価して期待する値と比べる。
+func checkEval(t *testing.T, cases []evalCase) {
+	t.Helper()
+	for _, c := range cases {
+		if got := evalText(c.src); got != c.want {
+			t.Errorf("%s => %s, want %s", c.src, got, c.want)
+		}
+	}
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
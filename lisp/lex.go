@@ -17,19 +17,67 @@ import (
 // 字句解析器 (Lexical analyzer)
 type Lex struct {
 	scanner.Scanner
-	Token rune // 現在のトークン
-	Value Any  // 現在のトークンの値
+	Token   rune        // 現在のトークン
+	Value   Any         // 現在のトークンの値
+	labels  map[int]Any // #n= で定義されたラベル
+	lazy    bool        // 式の直後のトークンを先読みしないか？
+	pending bool        // 次のトークンをまだ読んでいないか？
+	depth   int         // 読んでいる式の括弧の深さ
 }
 
+// 字句解析器に独自のトークン
+const (
+	StructToken   = -(iota + 100) // #S
+	LabelDefToken                 // #n= (lex.Value は n)
+	LabelRefToken                 // #n# (lex.Value は n)
+)
+
 // 入力ソースに対する字句解析器を返す。
+// 数は text/scanner ではなく arith.Parse の文法で解釈する。
 func NewLex(src io.Reader) *Lex {
+	lex := newLex(src)
+	lex.NextToken()
+	return lex
+}
+
+// 式を読み終えた後に次のトークンを先読みしない字句解析器を返す。
+// 対話的な入力から読むとき，式の直後で入力を待たずに済む。
+// Token は Read を呼ぶまで (AtEOF を除き) 更新されない。
+func NewLazyLex(src io.Reader) *Lex {
+	lex := newLex(src)
+	lex.lazy = true
+	lex.pending = true
+	return lex
+}
+
+func newLex(src io.Reader) *Lex {
 	var lex Lex
 	lex.Init(src)
-	lex.Mode &^= scanner.ScanChars | scanner.ScanRawStrings
-	lex.NextToken()
+	lex.Mode &^= scanner.ScanChars | scanner.ScanRawStrings |
+		scanner.ScanInts | scanner.ScanFloats
 	return &lex
 }
 
+// 入力の終わりならば true を返す。
+func (lex *Lex) AtEOF() bool {
+	if lex.pending {
+		lex.pending = false
+		lex.NextToken()
+	}
+	return lex.Token == scanner.EOF
+}
+
+// 式の最後になりうるトークンを読み終えて次へ進む。
+// 先読みしない字句解析器では，トップレベルの式を読み終えたときは
+// 次のトークンを次回の Read まで読まない。
+func (lex *Lex) advance() {
+	if lex.lazy && lex.depth == 0 {
+		lex.pending = true
+	} else {
+		lex.NextToken()
+	}
+}
+
 // 文脈情報を伴った error でパニックを発生させる。
 func (lex *Lex) Panic(msg string) {
 	panic(fmt.Errorf("%s: %q", msg, lex.TokenText()))
@@ -41,6 +89,7 @@ func (lex *Lex) Panic(msg string) {
 func (lex *Lex) NextToken() {
 	var token rune
 	var text string
+	var ok bool
 	for { // ; から行末までをコメントとして無視する
 		token = lex.Scan()
 		if token != ';' {
@@ -60,25 +109,41 @@ func (lex *Lex) NextToken() {
 	case '(', ')', '\'', scanner.EOF:
 		lex.Token = token
 		return
-	case scanner.Int, scanner.Float:
-		lex.Token = token
-		lex.Value, text = parseNumber(lex)
-		if text == "" {
+	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		if text, ok = lex.scanNumber(lex.TokenText()); ok {
 			return
 		}
-	case '-': // -数字... は負数として扱う
-		d := lex.Peek()
-		if '0' <= d && d <= '9' {
-			lex.Token = lex.Scan()
-			lex.Value, text = parseNumber(lex)
-			if text == "" {
-				lex.Value = arith.Subtract(0, lex.Value)
+	case '+', '-': // -1/2, +.5, -inf.0 などの符号付きの数
+		text = lex.TokenText()
+		d := unicode.ToLower(lex.Peek()) // +InF.0 なども数とする
+		if strings.ContainsRune("0123456789.in", d) {
+			if text, ok = lex.scanNumber(text); ok {
+				return
+			}
+		}
+	case '.': // .5 などの小数点で始まる数
+		text = lex.TokenText()
+		if d := lex.Peek(); '0' <= d && d <= '9' {
+			if text, ok = lex.scanNumber(text); ok {
 				return
 			}
-			text = "-" + text
-		} else {
-			text = "-"
 		}
+	case '#':
+		switch lex.Peek() {
+		case 'S', 's': // #S(型名 :フィールド 値...) は構造体
+			lex.Next()
+			lex.Token = StructToken
+			return
+		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			lex.scanLabel()
+			return
+		case 'X', 'x', 'O', 'o', 'B', 'b', 'D', 'd', 'E', 'e', 'I', 'i':
+			if text, ok = lex.scanNumber("#"); !ok {
+				panic(fmt.Errorf("invalid number: %q", text))
+			}
+			return
+		}
+		text = "#"
 	case scanner.String:
 		text = lex.TokenText()
 		s, err := strconv.Unquote(text)
@@ -99,82 +164,206 @@ func (lex *Lex) NextToken() {
 		text = fmt.Sprintf("%s%c", text, r)
 		lex.Next()
 	}
-	lex.Value = NewSymbol(text)
+	lex.Value = text // シンボルにするのは Read のときとする
 	lex.Token = scanner.Ident
 	return
 }
 
+// 空白と EOF のほかに単語や数の切れ目となる文字
+const delimiters = "()';,\""
+
+// 文字が単語や数の切れ目ならば true を返す。
+func isDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(delimiters, r) ||
+		r == scanner.EOF
+}
+
 // 次の文字を見てそこが単語の切れ目かどうかテストする。
+// 数ではない単語は . でも切れる。
 func peekAndTest(lex *Lex) (rune, bool) {
 	r := lex.Peek()
-	return r, (unicode.IsSpace(r) || strings.ContainsRune("()';.,", r) ||
-		r == scanner.EOF)
+	return r, isDelimiter(r) || r == '.'
 }
 
-func parseNumber(lex *Lex) (arith.Number, string) {
-	text := lex.TokenText()
-	num, ok := NumberFor(text)
-	if ok {
-		r, ok := peekAndTest(lex)
-		if ok {
-			return num, ""
+// prefix に続けて数の切れ目までの文字を読み，数として解釈する。
+// 解釈できれば lex.Token と lex.Value をセットして論理値に true を返す。
+// できなければ読んだ文字列と false を返す。
+func (lex *Lex) scanNumber(prefix string) (string, bool) {
+	text := prefix
+	for {
+		r := lex.Peek()
+		if isDelimiter(r) {
+			break
 		}
-		if r == '/' && lex.Token == scanner.Int { // 有理数リテラル 整数/整数
-			lex.Next()
-			t := lex.Scan()
-			text2 := lex.TokenText()
-			if t == scanner.Int {
-				num2, ok := NumberFor(text2)
-				if ok {
-					r, ok = peekAndTest(lex)
-					if ok {
-						return arith.DivideReal(num, num2), ""
-					}
-				}
-			}
-			return 0, text + "/" + text2
+		text = fmt.Sprintf("%s%c", text, r)
+		lex.Next()
+	}
+	num, ok := NumberFor(text)
+	if !ok {
+		return text, false
+	}
+	lex.Value = num
+	if arith.IsFloat(num) {
+		lex.Token = scanner.Float
+	} else {
+		lex.Token = scanner.Int
+	}
+	return text, true
+}
+
+// #n= または #n# の n 以降を読む。
+func (lex *Lex) scanLabel() {
+	n := 0
+	for d := lex.Peek(); '0' <= d && d <= '9'; d = lex.Peek() {
+		n = n*10 + int(d-'0')
+		if n > 1e6 {
+			panic(fmt.Errorf("label too large: #%d", n))
 		}
+		lex.Next()
+	}
+	switch lex.Next() {
+	case '=':
+		lex.Token = LabelDefToken
+	case '#':
+		lex.Token = LabelRefToken
+	default:
+		panic(fmt.Errorf("'=' or '#' expected after #%d", n))
 	}
-	return 0, text
+	lex.Value = n
 }
 
 // 字句解析器を使ってパースし Lisp 式を返す。
 // 空リストは *Cell 型の nil (つまり Any 型の非 nil) として返す。
 // EOF ならば Any 型の nil を返す。
+// #n= と #n# のラベルは一つの式の中でだけ有効である。
 func (lex *Lex) Read() Any {
+	if lex.AtEOF() {
+		return nil
+	}
+	lex.labels = nil
+	lex.depth = 0
+	return lex.read()
+}
+
+func (lex *Lex) read() Any {
 	switch lex.Token {
 	case ')':
 		lex.Panic("')' unexpected")
 	case '\'':
 		lex.NextToken()
-		return Cons(QuoteSymbol, Cons(lex.Read(), nil))
+		x := lex.read()
+		if x == nil {
+			return nil
+		}
+		return Cons(QuoteSymbol, Cons(x, nil))
 	case '(':
 		lex.NextToken()
+		lex.depth++
 		x, ok := parseListBody(lex)
 		if !ok {
 			return nil
 		}
 		return x
+	case LabelDefToken:
+		return lex.readLabeled(lex.Value.(int))
+	case LabelRefToken:
+		n := lex.Value.(int)
+		x, ok := lex.labels[n]
+		if !ok {
+			panic(fmt.Errorf("undefined label: #%d#", n))
+		}
+		lex.advance()
+		return x
+	case StructToken:
+		lex.NextToken()
+		if lex.Token != '(' {
+			lex.Panic("'(' expected after #S")
+		}
+		lex.NextToken()
+		lex.depth++
+		x, ok := parseListBody(lex)
+		if !ok {
+			return nil
+		}
+		return structFromList(x)
 	case scanner.EOF:
 		return nil
 	}
 	value := lex.Value
-	lex.NextToken()
-	if value == NilSymbol { // 識別子 nil は空リストとして扱う
-		return (*Cell)(nil)
+	if lex.Token == scanner.Ident {
+		switch text := value.(string); text {
+		case "nil": // 識別子 nil は空リストとして扱う
+			value = (*Cell)(nil)
+		case ".":
+			value = DotSymbol
+		default:
+			value = ReadSymbol(text)
+		}
 	}
+	lex.advance()
 	return value
 }
 
+// #n= に続く式を読んでラベル n を定義する。
+// リストと構造体は読み始める前に器を用意してラベルに登録するから，
+// その中から #n# で自分自身を参照できる。
+func (lex *Lex) readLabeled(n int) Any {
+	if lex.labels == nil {
+		lex.labels = make(map[int]Any)
+	}
+	lex.NextToken()
+	var x Any
+	switch lex.Token {
+	case '(':
+		head := &Cell{}
+		lex.labels[n] = head
+		if x = lex.read(); x == nil {
+			return nil
+		}
+		if c := x.(*Cell); c != nil {
+			*head = *c
+			x = head
+		}
+	case StructToken:
+		head := &Struct{}
+		lex.labels[n] = head
+		if x = lex.read(); x == nil {
+			return nil
+		}
+		*head = *x.(*Struct)
+		x = head
+	default:
+		if x = lex.read(); x == nil {
+			return nil
+		}
+	}
+	lex.labels[n] = x
+	return x
+}
+
 func parseListBody(lex *Lex) (*Cell, bool) {
 	if lex.Token == ')' {
-		lex.NextToken()
+		lex.depth--
+		lex.advance()
 		return nil, true
 	}
-	var e1 Any = lex.Read()
+	var e1 Any = lex.read()
 	if e1 == nil {
 		return nil, false
 	}
+	if e1 == DotSymbol && lex.Token != ')' { // (a b . (c d)) は (a b c d)
+		e2 := lex.read()
+		if e2 == nil {
+			return nil, false
+		}
+		if x, ok := e2.(*Cell); ok && lex.Token == ')' {
+			lex.depth--
+			lex.advance()
+			return x, true
+		}
+		e3, ok := parseListBody(lex) // さもなくば . は単なるシンボル
+		return Cons(e1, Cons(e2, e3)), ok
+	}
 	e2, ok := parseListBody(lex)
 	return Cons(e1, e2), ok
 }
@@ -0,0 +1,87 @@
+// H25.4/29 (鈴)
+
+package lisp
+
+import (
+	"github.com/pkelchte/tiny-lisp/arith"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// 数として読めた文字列は，それを arith.String で印字して読み直すと
+// 同じ型と値の数になることを確かめる。また，字句解析器が読む数は
+// arith.Parse が解釈する数と一致することを確かめる。
+func FuzzReadPrint(f *testing.F) {
+	for _, s := range []string{
+		"0", "42", "-7", "+5", "-3/4", "6/4", "0x1F", "#x1F", "#b-1010",
+		"#o17", "#e1.5", "#i1/3", "#x#e10", "1.5", ".5", "-.5", "1e400",
+		"1e-400", "-0.0", "+inf.0", "-inf.0", "+nan.0", "+InF.0", "-NaN.0", "+INF.0",
+		"123456789012345678901234567890", "-1/123456789012345678901",
+		"1+", "-", "abc", "#xZZ", "1/0",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		x, err := arith.Parse(s)
+		if err != nil {
+			return
+		}
+		printed := arith.String(x)
+		y := readOne(t, printed)
+		if !sameNumber(x, y) {
+			t.Errorf("%q => %s => %#v", s, printed, y)
+		}
+		if !strings.ContainsAny(s, " \t\n\r\v\f()';,\"") {
+			if z := readOne(t, s); !sameNumber(x, z) {
+				t.Errorf("%q read as %#v, parsed as %#v", s, z, x)
+			}
+		}
+	})
+}
+
+// 文字列から Lisp 式をひとつ読む。
+func readOne(t *testing.T, s string) (result Any) {
+	defer func() {
+		if e := recover(); e != nil {
+			t.Fatalf("%q: %v", s, e)
+		}
+	}()
+	return NewLex(strings.NewReader(s)).Read()
+}
+
+// 二つの数が同じ型と値ならば true を返す。NaN どうしも等しいとする。
+func sameNumber(a arith.Number, b Any) bool {
+	if arith.IsNaN(a) {
+		return arith.IsNaN(b)
+	}
+	if !Equal(a, b) {
+		return false
+	}
+	if r, ok := a.(*big.Rat); ok { // 分数は常に既約で分母が 1 でない
+		return !r.IsInt()
+	}
+	return true
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
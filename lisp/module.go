@@ -0,0 +1,236 @@
+// H25.5/7 (鈴)
+
+// このファイルはファイルの読み込みとモジュールを実装する。
+// (require 'foo) は foo がまだ provide されていなければ，ファイル foo.l を
+// 読み込みパスから探して load する。読み込みパスは変数 *load-path* の
+// 値であり，その初期値は環境変数 TINYLISP_PATH (: 区切り) から作る。
+// 読み込み中のファイルのディレクトリは *load-path* より先に探す。
+// (module foo (export a b) 式...) は式を foo 専用の環境で評価し，
+// export したシンボルだけを外側の環境に束縛する。
+
+package lisp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// 読み込みパスと provide 済みのモジュールと読み込み中のファイルの変数
+var LoadPathSymbol = NewSymbol("*load-path*")
+var ModulesSymbol = NewSymbol("*modules*")
+var LoadPathnameSymbol = NewSymbol("*load-pathname*")
+
+var exportSymbol = NewSymbol("export")
+
+// ソースファイルの拡張子
+const SourceFileExtension = ".l"
+
+// 環境変数 TINYLISP_PATH から読み込みパスの初期値を作る。
+func initialLoadPath() *Cell {
+	var dirs []Any
+	for _, dir := range filepath.SplitList(os.Getenv("TINYLISP_PATH")) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	if dirs == nil {
+		dirs = append(dirs, ".")
+	}
+	return listFunc(dirs).(*Cell)
+}
+
+// load や require で読み込むファイルを探す。
+// name が見つからなければ拡張子 .l を補ったものも試す。
+// ディレクトリを含まない名前は，読み込み中のファイルのディレクトリ，
+// *load-path* のディレクトリの順に探す。カレント・ディレクトリは
+// *load-path* にあるとき (TINYLISP_PATH が空のときの初期値) だけ探す。
+func FindFile(name string) (string, bool) {
+	candidates := []string{name}
+	if filepath.Ext(name) == "" {
+		candidates = append(candidates, name+SourceFileExtension)
+	}
+	var dirs []string
+	if filepath.IsAbs(name) || strings.ContainsRune(name, filepath.Separator) {
+		dirs = []string{""}
+	} else {
+		if v, ok := Globals.Lookup(LoadPathnameSymbol); ok {
+			if file, ok := v.(string); ok {
+				dirs = append(dirs, filepath.Dir(file))
+			}
+		}
+		if v, ok := Globals.Lookup(LoadPathSymbol); ok {
+			for x, _ := v.(*Cell); x != nil; x = x.Cdr {
+				dirs = append(dirs, x.Car.(string))
+			}
+		}
+	}
+	for _, dir := range dirs {
+		for _, c := range candidates {
+			path := filepath.Join(dir, c)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, true
+			}
+		}
+	}
+	return name, false
+}
+
+// ファイルを探して読み込み，各式をトップレベルの環境で評価する。
+// 見つからないか，式が不完全ならばパニックする。
+func Load(name string) {
+	path, ok := FindFile(name)
+	if !ok {
+		panic(fmt.Errorf("file not found: %s", name))
+	}
+	if !ReadAndEvalFile(path) {
+		panic(fmt.Errorf("incomplete expression: %s", path))
+	}
+}
+
+// モジュールが provide 済みならば true を返す。
+func IsProvided(name *Symbol) bool {
+	v, _ := Globals.Lookup(ModulesSymbol)
+	for x, _ := v.(*Cell); x != nil; x = x.Cdr {
+		if x.Car == name {
+			return true
+		}
+	}
+	return false
+}
+
+// モジュールを provide 済みとして *modules* に加える。
+func Provide(name *Symbol) {
+	requireLock.Lock()
+	defer requireLock.Unlock()
+	if !IsProvided(name) {
+		v, _ := Globals.Lookup(ModulesSymbol)
+		list, _ := v.(*Cell)
+		Globals.Set(ModulesSymbol, Cons(name, list))
+	}
+}
+
+// 読み込み中のモジュール (循環した require の検出用)
+var requiring = make(map[*Symbol]bool)
+var requireLock sync.Mutex
+
+// モジュールが provide 済みでなければファイルを読み込む。
+// file が "" ならばモジュール名に .l を付けたファイルを探す。
+// 読み込んだファイルが provide しなくても provide 済みとする。
+// 読み込んだならば true を返す。
+func Require(name *Symbol, file string) bool {
+	requireLock.Lock()
+	if IsProvided(name) {
+		requireLock.Unlock()
+		return false
+	}
+	if requiring[name] {
+		requireLock.Unlock()
+		panic(fmt.Errorf("circular require: %s", name.string))
+	}
+	requiring[name] = true
+	requireLock.Unlock()
+	defer func() {
+		requireLock.Lock()
+		delete(requiring, name)
+		requireLock.Unlock()
+	}()
+	if file == "" {
+		file = name.string
+	}
+	Load(file)
+	Provide(name)
+	return true
+}
+
+// モジュールの名前をその環境に記録するための (intern されない) シンボル
+var moduleNameSymbol = &Symbol{string: "#<module name>"}
+
+// env がモジュールの環境ならばその名前を返す。さもなくば nil を返す。
+func moduleOf(env *Env) *Symbol {
+	env.Lock.Lock()
+	defer env.Lock.Unlock()
+	name, _ := env.Table[moduleNameSymbol].(*Symbol)
+	return name
+}
+
+// (load filename)
+func loadFunc(a []Any) Any {
+	CheckArity(1, a)
+	Load(a[0].(string))
+	return TSymbol
+}
+
+// (provide name)
+func provideFunc(a []Any) Any {
+	CheckArity(1, a)
+	name := a[0].(*Symbol)
+	Provide(name)
+	return name
+}
+
+// (require name [filename]) 読み込んだならば t を返す。
+func requireFunc(a []Any) Any {
+	CheckArity(-1, a)
+	file := ""
+	if len(a) == 2 {
+		file = a[1].(string)
+	} else {
+		CheckArity(1, a)
+	}
+	return LispBool(Require(a[0].(*Symbol), file))
+}
+
+// (module name [(export symbol...)] expression...)
+// 式をモジュール専用の環境で評価する。その中の defun と未定義の変数への
+// setq はモジュールの環境に束縛する。評価後，export したシンボルを
+// その値と共に外側の環境に束縛し，モジュールを provide 済みとする。
+func moduleForm(x *Cell, env *Env) (Any, *Env) {
+	a, b := CheckForUnaryAndRest(x)
+	name := a.(*Symbol)
+	var exports *Cell
+	if b != nil {
+		if c, ok := b.Car.(*Cell); ok && c != nil && c.Car == exportSymbol {
+			exports, b = c.Cdr, b.Cdr
+		}
+	}
+	menv := &Env{map[*Symbol]Any{moduleNameSymbol: name}, env, sync.Mutex{}}
+	evalBody(b, menv)
+	for ; exports != nil; exports = exports.Cdr {
+		sym := exports.Car.(*Symbol)
+		menv.Lock.Lock()
+		val, ok := menv.Table[sym]
+		menv.Lock.Unlock()
+		if !ok {
+			panic(fmt.Errorf("%s: exported symbol not defined: %s",
+				name.string, sym.string))
+		}
+		env.Set(sym, val)
+	}
+	Provide(name)
+	return name, nil
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,115 @@
+// H25.5/13 (鈴)
+
+package lisp
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestModule(t *testing.T) {
+	checkEval(t, []evalCase{
+		{`(module mod-geo (export mod-area)
+		    (defun mod-sq (x) (* x x))
+		    (setq mod-pi 3)
+		    (defun mod-area (r) (* mod-pi (mod-sq r))))`, "mod-geo"},
+		{"(mod-area 2)", "12"},
+		{"(list (boundp 'mod-sq) (boundp 'mod-pi))", "(() ())"},
+		{"(car *modules*)", "mod-geo"},
+		{`(defun mod-car (x) 1)
+		  (module mod-shadow (export mod-f)
+		    (defun mod-car (x) 2)
+		    (defun mod-f () (mod-car 0)))
+		  (list (mod-f) (mod-car 0))`, "(2 1)"},
+		{"(module mod-bad (export mod-nope) (setq mod-y 1))",
+			"error: mod-bad: exported symbol not defined: mod-nope"},
+	})
+}
+
+func TestRequire(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"req-a.l": "(setq req-count (+ req-count 1))",
+		"req-b.l": "(require 'req-c)",
+		"req-c.l": "(require 'req-b)",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old, _ := Globals.Lookup(LoadPathSymbol)
+	defer Globals.Set(LoadPathSymbol, old)
+	checkEval(t, []evalCase{
+		{"(setq *load-path* (list " + strconv.Quote(dir) + ")) (setq req-count 0)", "0"},
+		{"(list (require 'req-a) (require 'req-a) req-count)", "(t () 1)"},
+		{"(car *modules*)", "req-a"},
+		{"(require 'req-b)", "error: circular require: req-b"},
+		{"(require 'req-none)", "error: file not found: req-none"},
+	})
+}
+
+func TestFindFileOrder(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"p1/ord-b.l":  `(setq ord-b "p1")`,
+		"p1/ord-c.l":  `(setq ord-c "p1")`,
+		"p2/ord-c.l":  `(setq ord-c "p2")`,
+		"sub/ord-a.l": "(require 'ord-b)",
+		"sub/ord-b.l": `(setq ord-b "sub")`,
+		"cwd/ord-d.l": `(setq ord-d "cwd")`,
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(dir, "cwd")); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	old, _ := Globals.Lookup(LoadPathSymbol)
+	defer Globals.Set(LoadPathSymbol, old)
+	p1 := strconv.Quote(filepath.Join(dir, "p1"))
+	p2 := strconv.Quote(filepath.Join(dir, "p2"))
+	checkEval(t, []evalCase{
+		{"(setq *load-path* (list " + p1 + " " + p2 + ")) (require 'ord-c) ord-c",
+			`"p1"`},
+		{"(load " + strconv.Quote(filepath.Join(dir, "sub", "ord-a")) + ") ord-b",
+			`"sub"`},
+		{`(load "ord-d")`, "error: file not found: ord-d"},
+		{`(setq *load-path* (list ".")) (load "ord-d") ord-d`, `"cwd"`},
+	})
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,474 @@
+// H25.5/8 (鈴)
+
+// このファイルはシンボルのパッケージを実装する。
+// シンボルはパッケージごとの表に名前で登録 (intern) される。同じ
+// パッケージの同じ名前のシンボルは常に同じアドレスだから eq で比べられる。
+// 組込みのシンボルはすべてパッケージ lisp にあり，すべて外部シンボルである。
+// 対話セッションはパッケージ user で始まる。user は lisp を use する。
+// 読み込み時に pkg:sym は pkg の外部シンボルを，pkg::sym は pkg の
+// 内部シンボルを表す。: で始まるシンボルはキーワードであり lisp にある。
+
+package lisp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// パッケージ型
+type Package struct {
+	Name     string
+	symbols  map[string]*Symbol // このパッケージにあるシンボル
+	external map[*Symbol]bool   // そのうち export されたシンボル
+	uses     []*Package         // 外部シンボルを継承するパッケージ
+	lock     sync.Mutex
+}
+
+var packages = make(map[string]*Package)
+var packageLock sync.Mutex
+
+// 組込みのパッケージ
+var LispPackage = NewPackage("lisp")
+var UserPackage = NewPackage("user", LispPackage)
+
+// 現在のパッケージの変数
+var PackageSymbol = NewSymbol("*package*")
+
+// 新しいパッケージを作る。同名のパッケージがあればパニックする。
+func NewPackage(name string, uses ...*Package) *Package {
+	packageLock.Lock()
+	defer packageLock.Unlock()
+	if _, ok := packages[name]; ok {
+		panic(fmt.Errorf("package already exists: %s", name))
+	}
+	p := &Package{Name: name, symbols: make(map[string]*Symbol),
+		external: make(map[*Symbol]bool), uses: uses}
+	packages[name] = p
+	return p
+}
+
+// すべてのパッケージを名前の順に返す。
+func Packages() []*Package {
+	packageLock.Lock()
+	defer packageLock.Unlock()
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]*Package, len(names))
+	for i, name := range names {
+		result[i] = packages[name]
+	}
+	return result
+}
+
+// 名前に対するパッケージを返す。無ければ nil を返す。
+func FindPackage(name string) *Package {
+	packageLock.Lock()
+	defer packageLock.Unlock()
+	return packages[name]
+}
+
+// パッケージの文字列表現 (表示用)
+func (p *Package) String() string {
+	return "#<package " + p.Name + ">"
+}
+
+// パッケージ q の外部シンボルを継承する。
+func (p *Package) Use(q *Package) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, u := range p.uses {
+		if u == q {
+			return
+		}
+	}
+	p.uses = append(p.uses, q)
+}
+
+// このパッケージにあるシンボルを返す。順序は不定である。
+func (p *Package) Symbols() []*Symbol {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	result := make([]*Symbol, 0, len(p.symbols))
+	for _, sym := range p.symbols {
+		result = append(result, sym)
+	}
+	return result
+}
+
+// 外部シンボルならば true を返す。
+func (p *Package) IsExternal(sym *Symbol) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.external[sym]
+}
+
+// 名前に対する外部シンボルを探す。
+func (p *Package) FindExternal(name string) (*Symbol, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	sym, ok := p.symbols[name]
+	return sym, ok && p.external[sym]
+}
+
+// このパッケージから名前で参照できるシンボル
+// (このパッケージにあるか use したパッケージの外部シンボル) を探す。
+func (p *Package) FindSymbol(name string) (*Symbol, bool) {
+	p.lock.Lock()
+	sym, ok := p.symbols[name]
+	uses := p.uses
+	p.lock.Unlock()
+	if ok {
+		return sym, true
+	}
+	for _, q := range uses {
+		if sym, ok := q.FindExternal(name); ok {
+			return sym, true
+		}
+	}
+	return nil, false
+}
+
+// 名前で参照できるシンボルを返す。無ければこのパッケージに作る。
+// パッケージ lisp に作ったシンボルは外部シンボルとする。
+func (p *Package) Intern(name string) *Symbol {
+	if sym, ok := p.FindSymbol(name); ok {
+		return sym
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	sym, ok := p.symbols[name]
+	if !ok {
+		sym = &Symbol{string: name, Package: p}
+		p.symbols[name] = sym
+		if p == LispPackage {
+			p.external[sym] = true
+		}
+	}
+	return sym
+}
+
+// シンボルを外部シンボルにする。他のパッケージのシンボルならば
+// このパッケージに取り込んでから export する。このパッケージを use する
+// パッケージに同名の別のシンボルがあればパニックする。
+func (p *Package) Export(sym *Symbol) {
+	packageLock.Lock()
+	for _, q := range packages {
+		if q == p {
+			continue
+		}
+		q.lock.Lock()
+		other, ok := q.symbols[sym.string]
+		uses := q.uses
+		q.lock.Unlock()
+		if ok && other != sym {
+			for _, u := range uses {
+				if u == p {
+					packageLock.Unlock()
+					panic(fmt.Errorf("name conflict in %s: %s",
+						q.Name, sym.string))
+				}
+			}
+		}
+	}
+	packageLock.Unlock()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if other, ok := p.symbols[sym.string]; ok && other != sym {
+		panic(fmt.Errorf("name conflict in %s: %s", p.Name, sym.string))
+	}
+	p.symbols[sym.string] = sym
+	p.external[sym] = true
+}
+
+// 変数 *package* の値である現在のパッケージを返す。
+func CurrentPackage() *Package {
+	v, _ := Globals.Lookup(PackageSymbol)
+	p, ok := v.(*Package)
+	if !ok {
+		panic(fmt.Errorf("*package* is not a package: %s", StringFor(v)))
+	}
+	return p
+}
+
+// 現在のパッケージで名前に対するシンボルを返す。
+func Intern(name string) *Symbol {
+	return CurrentPackage().Intern(name)
+}
+
+// 読み込んだ識別子をシンボルにする。pkg:sym と pkg::sym を解釈する。
+func ReadSymbol(text string) *Symbol {
+	i := strings.IndexRune(text, ':')
+	if i < 0 {
+		return Intern(text)
+	} else if i == 0 {
+		return NewSymbol(text) // キーワード
+	}
+	p := FindPackage(text[:i])
+	if p == nil {
+		panic(fmt.Errorf("package not found: %s", text))
+	}
+	name := text[i+1:]
+	internal := strings.HasPrefix(name, ":")
+	if internal {
+		name = name[1:]
+	}
+	if name == "" || strings.ContainsRune(name, ':') {
+		panic(fmt.Errorf("invalid symbol: %s", text))
+	}
+	if internal {
+		return p.Intern(name)
+	}
+	sym, ok := p.FindExternal(name)
+	if !ok {
+		panic(fmt.Errorf("not an external symbol: %s", text))
+	}
+	return sym
+}
+
+// prefix で始まるシンボルの名前を名前の順に返す。対話セッションの
+// 補完に使う。pkg: または pkg:: で始まる prefix ならば pkg の外部または
+// すべてのシンボルを，さもなくば現在のパッケージから参照できるシンボルと
+// パッケージ名 (: を付ける) を候補とする。
+func Completions(prefix string) []string {
+	var names []string
+	add := func(name string) {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	if i := strings.IndexRune(prefix, ':'); i > 0 {
+		p := FindPackage(prefix[:i])
+		if p == nil {
+			return nil
+		}
+		internal := strings.HasPrefix(prefix[i+1:], ":")
+		for _, sym := range p.Symbols() {
+			if internal {
+				add(p.Name + "::" + sym.string)
+			} else if p.IsExternal(sym) {
+				add(p.Name + ":" + sym.string)
+			}
+		}
+	} else {
+		cur := CurrentPackage()
+		for _, sym := range cur.Symbols() {
+			add(sym.string)
+		}
+		cur.lock.Lock()
+		uses := cur.uses
+		cur.lock.Unlock()
+		for _, q := range uses {
+			for _, sym := range q.Symbols() {
+				if q.IsExternal(sym) {
+					add(sym.string)
+				}
+			}
+		}
+		for _, p := range Packages() {
+			if p != cur {
+				add(p.Name + ":")
+			}
+		}
+	}
+	sort.Strings(names)
+	result := names[:0]
+	for i, name := range names { // 重複を除く
+		if i == 0 || name != names[i-1] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// シンボルを現在のパッケージから読める形で文字列にする。
+// 現在のパッケージから参照できなければパッケージ名を前置する。
+func stringForSymbol(sym *Symbol) string {
+	p := sym.Package
+	if p == nil || sym.IsKeyword() {
+		return sym.string
+	}
+	if s, ok := CurrentPackage().FindSymbol(sym.string); ok && s == sym {
+		return sym.string
+	}
+	if p.IsExternal(sym) {
+		return p.Name + ":" + sym.string
+	}
+	return p.Name + "::" + sym.string
+}
+
+// 引数のシンボルまたは文字列またはパッケージからパッケージを得る。
+func packageArg(a Any) *Package {
+	var name string
+	switch x := a.(type) {
+	case *Package:
+		return x
+	case *Symbol:
+		name = x.string
+	case string:
+		name = x
+	default:
+		panic(fmt.Errorf("package designator expected: %s", StringFor(a)))
+	}
+	if p := FindPackage(name); p != nil {
+		return p
+	}
+	panic(fmt.Errorf("package not found: %s", name))
+}
+
+// 引数のシンボルまたは文字列から名前を得る。
+func nameArg(a Any) string {
+	switch x := a.(type) {
+	case *Symbol:
+		return x.string
+	case string:
+		return x
+	}
+	panic(fmt.Errorf("symbol or string expected: %s", StringFor(a)))
+}
+
+// (defpackage name [(:use package...)] [(:export symbol...)])
+// パッケージを作る (既にあればそれに追加する)。:use を省略すると
+// lisp を use する。export するシンボルはその名前で新しいパッケージに
+// intern する。
+func defpackageForm(x *Cell, env *Env) (Any, *Env) {
+	a, b := CheckForUnaryAndRest(x)
+	name := nameArg(a)
+	var uses []*Package
+	var exports []string
+	useGiven := false
+	for ; b != nil; b = b.Cdr {
+		option, ok := b.Car.(*Cell)
+		if !ok || option == nil {
+			panic(fmt.Errorf("(:use ...) or (:export ...) expected: %s",
+				StringFor(b.Car)))
+		}
+		switch option.Car {
+		case Keyword("use"):
+			useGiven = true
+			for y := option.Cdr; y != nil; y = y.Cdr {
+				uses = append(uses, packageArg(y.Car))
+			}
+		case Keyword("export"):
+			for y := option.Cdr; y != nil; y = y.Cdr {
+				exports = append(exports, nameArg(y.Car))
+			}
+		default:
+			panic(fmt.Errorf("unknown defpackage option: %s",
+				StringFor(option.Car)))
+		}
+	}
+	if !useGiven {
+		uses = append(uses, LispPackage)
+	}
+	p := FindPackage(name)
+	if p == nil {
+		p = NewPackage(name)
+	}
+	for _, q := range uses {
+		p.Use(q)
+	}
+	for _, s := range exports {
+		p.Export(p.Intern(s))
+	}
+	return p, nil
+}
+
+// (in-package name) 現在のパッケージを変える。
+// ファイルを読み込む間の変更は読み込み後に元に戻る (ReadAndEvalScript)。
+func inPackageForm(x *Cell, env *Env) (Any, *Env) {
+	p := packageArg(CheckForUnary(x))
+	Globals.Set(PackageSymbol, p)
+	return p, nil
+}
+
+// (export symbol-or-symbols [package])
+func exportFunc(a []Any) Any {
+	CheckArity(-1, a)
+	p := CurrentPackage()
+	if len(a) == 2 {
+		p = packageArg(a[1])
+	} else {
+		CheckArity(1, a)
+	}
+	switch x := a[0].(type) {
+	case *Symbol:
+		p.Export(x)
+	case *Cell:
+		for ; x != nil; x = x.Cdr {
+			p.Export(x.Car.(*Symbol))
+		}
+	default:
+		panic(fmt.Errorf("symbol or list expected: %s", StringFor(x)))
+	}
+	return TSymbol
+}
+
+// (find-package name) 無ければ nil を返す。
+func findPackageFunc(a []Any) Any {
+	CheckArity(1, a)
+	if p, ok := a[0].(*Package); ok {
+		return p
+	}
+	if p := FindPackage(nameArg(a[0])); p != nil {
+		return p
+	}
+	return (*Cell)(nil)
+}
+
+// (intern string [package])
+func internFunc(a []Any) Any {
+	CheckArity(-1, a)
+	if len(a) == 2 {
+		return packageArg(a[1]).Intern(a[0].(string))
+	}
+	CheckArity(1, a)
+	return Intern(a[0].(string))
+}
+
+// (symbol-name symbol)
+func symbolNameFunc(a []Any) Any {
+	CheckArity(1, a)
+	return a[0].(*Symbol).string
+}
+
+// (symbol-package symbol)
+func symbolPackageFunc(a []Any) Any {
+	CheckArity(1, a)
+	if p := a[0].(*Symbol).Package; p != nil {
+		return p
+	}
+	return (*Cell)(nil)
+}
+
+// (package-name package)
+func packageNameFunc(a []Any) Any {
+	CheckArity(1, a)
+	return packageArg(a[0]).Name
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
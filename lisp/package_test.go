@@ -0,0 +1,96 @@
+// H25.5/13 (鈴)
+
+package lisp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestPackage(t *testing.T) {
+	defer evalText("(in-package user)")
+	checkEval(t, []evalCase{
+		{"(defpackage pk-util (:export pk-twice))", "#<package pk-util>"},
+		{"(in-package pk-util)", "#<package pk-util>"},
+		{"(defun pk-twice (x) (* 2 x))", "pk-twice"},
+		{"(defun pk-hidden () 1)", "pk-hidden"},
+		{"(in-package user)", "#<package user>"},
+		{"(pk-util:pk-twice 21)", "42"},
+		{"(pk-util::pk-hidden)", "1"},
+		{"pk-util:pk-hidden", "error: not an external symbol: pk-util:pk-hidden"},
+		{"pk-nope:x", "error: package not found: pk-nope:x"},
+		{"(eq 'pk-twice 'pk-util:pk-twice)", "()"},
+		{"(symbol-package 'pk-util:pk-twice)", "#<package pk-util>"},
+		{"(symbol-name 'pk-util:pk-twice)", `"pk-twice"`},
+		{"(package-name (find-package 'pk-util))", `"pk-util"`},
+		{"(find-package 'pk-none)", "()"},
+		{`(eq (intern "pk-twice") 'pk-twice)`, "t"},
+		{"(symbol-package 'car)", "#<package lisp>"},
+	})
+}
+
+func TestInPackageInFile(t *testing.T) {
+	defer evalText("(in-package user)")
+	dir := t.TempDir()
+	files := map[string]string{
+		"pk-file.l": "(defpackage pk-file (:export pk-f)) (in-package pk-file)\n" +
+			"(defun pk-f () 'pk-file)\n",
+		"pk-bad.l": "(in-package pk-file)\npk-unbound\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load := func(name string) string {
+		return "(load " + strconv.Quote(filepath.Join(dir, name)) + ")"
+	}
+	checkEval(t, []evalCase{
+		{load("pk-file.l") + " *package*", "#<package user>"},
+		{"(pk-file:pk-f)", "pk-file::pk-file"},
+		{load("pk-bad.l"), "error: unbound symbol: pk-unbound"},
+		{"*package*", "#<package user>"},
+	})
+}
+
+func TestCompletions(t *testing.T) {
+	evalText("(defpackage pk-comp (:export pk-comp-ext))")
+	evalText("(pk-comp::pk-comp-int)")
+	for _, c := range []struct {
+		prefix string
+		want   []string
+	}{
+		{"pk-comp:", []string{"pk-comp:pk-comp-ext"}},
+		{"pk-comp::pk-comp-i", []string{"pk-comp::pk-comp-int"}},
+		{"pk-com", []string{"pk-comp", "pk-comp-ext", "pk-comp:"}},
+	} {
+		if got := Completions(c.prefix); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s => %v, want %v", c.prefix, got, c.want)
+		}
+	}
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
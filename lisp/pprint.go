@@ -0,0 +1,225 @@
+// H25.5/2 (鈴)
+
+// このファイルはリストを右マージンまでで改行し字下げして表示する
+// プリティプリンタを実装する。
+// 式を一度たどって StringFor と同じ規則 (MaxPrintRecur と循環リストの
+// 検出) で木を作り，その木を右マージンに合わせて配置する。
+
+package lisp
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// この変数の値が真ならば，対話セッションは結果をプリティプリントする。
+var PrintPrettySymbol = NewSymbol("*print-pretty*")
+
+// この変数の値はプリティプリンタの右マージン (桁数) である。
+var PrintRightMarginSymbol = NewSymbol("*print-right-margin*")
+
+// *print-right-margin* が整数でないときの右マージン
+const DefaultRightMargin = 72
+
+// 特別な字下げをする式の規則.
+// 先頭の distinguished 個の引数は関数名の後に続け (入りきらなければ
+// 4 桁字下げし)，残りの本体は 2 桁字下げして各行に置く。
+// joined ならば先頭の引数群を入るかぎり同じ行に置く。
+type indentRule struct {
+	distinguished int
+	joined        bool
+}
+
+var indentRules = map[string]indentRule{
+	"defun":     {2, true},
+	"lambda":    {1, true},
+	"let":       {1, true},
+	"defstruct": {1, true},
+	"if":        {2, false},
+	"progn":     {0, false},
+}
+
+// プリティプリントの木の節
+type ppNode struct {
+	flat     string    // 一行で表示したときの文字列
+	isSymbol bool      // シンボルの原子か？
+	quoted   *ppNode   // 'x ならば x の節
+	items    []*ppNode // リストならば要素の節 (原子ならば nil)
+	isList   bool
+}
+
+// 右マージン margin でプリティプリントした Lisp 式の文字列表現を返す。
+// 変数 *print-circle* が真ならば StringFor と同じくラベルを付けて
+// 一行で表す。
+func PrettyStringFor(a Any, margin int) string {
+	if PrintCircle() {
+		return StringWithLabelsFor(a)
+	}
+	n := buildPPNode(a, MaxPrintRecur, make(map[Any]bool))
+	return layout(n, 0, margin)
+}
+
+// 変数 *print-right-margin* の値を返す。
+func RightMargin() int {
+	if v, ok := Globals.Lookup(PrintRightMarginSymbol); ok {
+		if i, ok := v.(int32); ok && i > 0 {
+			return int(i)
+		}
+	}
+	return DefaultRightMargin
+}
+
+// 変数 *print-pretty* の値が真ならば true を返す。
+func PrintPretty() bool {
+	v, ok := Globals.Lookup(PrintPrettySymbol)
+	return ok && v != (*Cell)(nil)
+}
+
+// stringFor と同じ規則で式をたどって節を作る。
+func buildPPNode(a Any, recurLevel int, printed map[Any]bool) *ppNode {
+	x, ok := a.(*Cell)
+	if !ok || x == nil {
+		_, isSymbol := a.(*Symbol)
+		return &ppNode{flat: stringFor(a, recurLevel, printed),
+			isSymbol: isSymbol}
+	}
+	if x.Car == QuoteSymbol && x.Cdr != nil && x.Cdr.Cdr == nil {
+		q := buildPPNode(x.Cdr.Car, recurLevel, printed)
+		return &ppNode{flat: "'" + q.flat, quoted: q}
+	}
+	n := &ppNode{isList: true}
+	s := make([]string, 0, 10)
+	var added []*Cell
+	var y *Cell
+	for y = x; y != nil; y = y.Cdr { // stringForList と同様
+		if _, ok := printed[y]; ok {
+			recurLevel--
+			if recurLevel < 0 {
+				n.items = append(n.items, &ppNode{flat: "..."})
+				s = append(s, "...")
+				break
+			}
+		} else {
+			printed[y] = true
+			added = append(added, y)
+			recurLevel = MaxPrintRecur
+		}
+		e := buildPPNode(y.Car, recurLevel, printed)
+		n.items = append(n.items, e)
+		s = append(s, e.flat)
+	}
+	if y == nil {
+		for _, y := range added {
+			delete(printed, y)
+		}
+	}
+	n.flat = "(" + strings.Join(s, " ") + ")"
+	return n
+}
+
+// 文字列を表示した後の桁位置を返す。
+func columnAfter(s string, col int) int {
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		return utf8.RuneCountInString(s[i+1:])
+	}
+	return col + utf8.RuneCountInString(s)
+}
+
+// 桁位置 col から始めて右マージン margin に収まるように節を配置する。
+func layout(n *ppNode, col, margin int) string {
+	if columnAfter(n.flat, col) <= margin {
+		return n.flat
+	}
+	if n.quoted != nil {
+		return "'" + layout(n.quoted, col+1, margin)
+	}
+	if !n.isList || len(n.items) == 0 {
+		return n.flat
+	}
+	var b strings.Builder
+	b.WriteString("(")
+	head := n.items[0]
+	args := n.items[1:]
+	newline := func(indent int) {
+		b.WriteString("\n")
+		b.WriteString(strings.Repeat(" ", indent))
+	}
+	if !head.isSymbol { // データのリスト: 入るかぎり同じ行に詰める
+		cur := col + 1
+		for i, e := range n.items {
+			if i > 0 {
+				if cur+1+utf8.RuneCountInString(e.flat) < margin {
+					b.WriteString(" ")
+					cur++
+				} else {
+					newline(col + 1)
+					cur = col + 1
+				}
+			}
+			s := layout(e, cur, margin)
+			b.WriteString(s)
+			cur = columnAfter(s, cur)
+		}
+		b.WriteString(")")
+		return b.String()
+	}
+	b.WriteString(head.flat)
+	cur := col + 1 + utf8.RuneCountInString(head.flat)
+	if rule, ok := indentRules[head.flat]; ok { // 特別な字下げをする式
+		for i, e := range args {
+			switch {
+			case i >= rule.distinguished:
+				newline(col + 2)
+				cur = col + 2
+			case i == 0 || rule.joined &&
+				cur+1+utf8.RuneCountInString(e.flat) < margin:
+				b.WriteString(" ")
+				cur++
+			default:
+				newline(col + 4)
+				cur = col + 4
+			}
+			s := layout(e, cur, margin)
+			b.WriteString(s)
+			cur = columnAfter(s, cur)
+		}
+	} else if len(args) > 0 { // 関数呼出し: 引数を第１引数にそろえる
+		argCol := cur + 1
+		if argCol-col > 12 { // 関数名が長ければ 2 桁だけ字下げする
+			argCol = col + 2
+			newline(argCol)
+		} else {
+			b.WriteString(" ")
+		}
+		for i, e := range args {
+			if i > 0 {
+				newline(argCol)
+			}
+			b.WriteString(layout(e, argCol, margin))
+		}
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,66 @@
+// H25.5/13 (鈴)
+
+package lisp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPrettyStringFor(t *testing.T) {
+	for _, c := range []struct {
+		src    string
+		margin int
+		want   string
+	}{
+		{"(defun fact (n) (if (= n 0) 1 (* n (fact (- n 1)))))", 30,
+			"(defun fact (n)\n  (if (= n 0)\n      1\n    (* n (fact (- n 1)))))"},
+		{"(1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16 17 18 19 20)", 30,
+			"(1 2 3 4 5 6 7 8 9 10 11 12\n 13 14 15 16 17 18 19 20)"},
+		{"(let ((a 1) (b 2)) (list a b a b a b a b))", 30,
+			"(let ((a 1) (b 2))\n  (list a b a b a b a b))"},
+		{"'(a b)", 30, "'(a b)"},
+		{"(f x)", 30, "(f x)"},
+	} {
+		x := NewLex(strings.NewReader(c.src)).Read()
+		if got := PrettyStringFor(x, c.margin); got != c.want {
+			t.Errorf("%s =>\n%s\nwant\n%s", c.src, got, c.want)
+		}
+	}
+}
+
+func TestReadEvalPrintPretty(t *testing.T) {
+	defer evalText(fmt.Sprintf("(setq *print-pretty* nil) (setq *print-right-margin* %d)",
+		DefaultRightMargin))
+	evalText("(setq *print-pretty* t) (setq *print-right-margin* 20)")
+	var out strings.Builder
+	ReadEvalPrint(strings.NewReader("'(a b) '(1111 2222 3333 4444 5555)"), &out)
+	want := "'(a b) => (a b)\n" +
+		"'(1111 2222 3333 4444 5555) => \n(1111 2222 3333\n 4444 5555)\n"
+	if got := out.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,365 @@
+// H25.5/12 (鈴)
+
+// このファイルは Lisp の関数のプロファイラを実装する。
+// プロファイル中に defun した関数は呼び出しの回数と時間を数える。
+// 呼び出し元は動的なスタックではなく，呼び出した式を評価した環境から
+// たどれる最も内側の関数の呼び出しとする。普通の関数の呼び出しでは
+// 両者は一致する。future の式は呼び出し元の下の (future) として数え，
+// その時間は呼び出し元から差し引かない。
+// プロファイル中の関数は時間を計るため末尾呼び出しを最適化しない。
+// 結果は関数ごとの一覧として，また Go の pprof で読める形式で書き出せる。
+
+package lisp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// プロファイルの結果
+type Profile struct {
+	start    time.Time
+	duration time.Duration
+	root     *callNode
+	lock     sync.Mutex // 呼び出しの木と関数の表を排他する
+	funcs    map[*Symbol]*profiledFunc
+	future   *profiledFunc
+}
+
+// プロファイルする関数
+type profiledFunc struct {
+	id   uint64
+	name string
+	file string
+}
+
+// 呼び出しの木の節. 根から節までの関数の列ごとに回数と時間を数える。
+// 列の中ですでに呼び出している関数の呼び出しはその節で数える。
+type callNode struct {
+	fn       *profiledFunc
+	parent   *callNode
+	children map[*profiledFunc]*callNode
+	calls    int64 // 呼び出しの回数
+	self     int64 // 呼び出した関数を除いた時間 (ナノ秒)
+}
+
+// 実行中の関数の呼び出し
+type callFrame struct {
+	node     *callNode
+	parent   *callFrame
+	detached bool // 時間を呼び出し元から差し引かないならば true
+	start    time.Time
+	children int64 // 呼び出した関数の時間 (ナノ秒)
+}
+
+// 関数の呼び出しを環境に記録するための (intern されない) シンボル
+var frameSymbol = &Symbol{string: "#<profile frame>"}
+
+// 実行中のプロファイル. プロファイルしていなければ nil
+var currentProfile atomic.Pointer[Profile]
+
+// プロファイルを始める。これより後に defun した関数を数える。
+func StartProfiling() {
+	p := &Profile{start: time.Now(), root: &callNode{},
+		funcs: make(map[*Symbol]*profiledFunc)}
+	p.future = &profiledFunc{id: 1, name: "(future)"}
+	currentProfile.Store(p)
+}
+
+// プロファイルを終えて結果を返す。プロファイルしていなければ nil を返す。
+func StopProfiling() *Profile {
+	p := currentProfile.Swap(nil)
+	if p != nil {
+		p.lock.Lock()
+		p.duration = time.Since(p.start)
+		p.lock.Unlock()
+	}
+	return p
+}
+
+// 関数 name をプロファイルの対象として登録する。
+func (p *Profile) function(name *Symbol) *profiledFunc {
+	file := ""
+	if s, ok := Globals.Get(LoadPathnameSymbol).(string); ok {
+		file = s
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	fn, ok := p.funcs[name]
+	if !ok {
+		fn = &profiledFunc{id: uint64(len(p.funcs) + 2), name: StringFor(name),
+			file: file}
+		p.funcs[name] = fn
+	}
+	return fn
+}
+
+// 環境 env で式を評価して fn を呼び出し始めたことを記録し，呼び出しを
+// 環境 callee に束縛する。
+func (p *Profile) enter(fn *profiledFunc, env, callee *Env, detached bool) *callFrame {
+	var parent *callFrame
+	node := p.root
+	if v, ok := env.Lookup(frameSymbol); ok {
+		parent = v.(*callFrame)
+		node = parent.node
+	}
+	p.lock.Lock()
+	child := node
+	for child.fn != nil && child.fn != fn { // 再帰呼び出しは畳む
+		child = child.parent
+	}
+	if child.fn == nil {
+		child = node.children[fn]
+	}
+	if child == nil {
+		if node.children == nil {
+			node.children = make(map[*profiledFunc]*callNode)
+		}
+		child = &callNode{fn: fn, parent: node}
+		node.children[fn] = child
+	}
+	p.lock.Unlock()
+	fr := &callFrame{node: child, parent: parent, detached: detached,
+		start: time.Now()}
+	callee.define(frameSymbol, fr)
+	return fr
+}
+
+// 呼び出しの終わりを記録する。
+func (fr *callFrame) exit() {
+	elapsed := int64(time.Since(fr.start))
+	self := elapsed - atomic.LoadInt64(&fr.children)
+	if self < 0 {
+		self = 0
+	}
+	atomic.AddInt64(&fr.node.calls, 1)
+	atomic.AddInt64(&fr.node.self, self)
+	if fr.parent != nil && !fr.detached {
+		atomic.AddInt64(&fr.parent.children, elapsed)
+	}
+}
+
+// 名前 name の関数の lambda 式の本体 b を，呼び出しを数えるように
+// 評価する関数を返す。プロファイルしていなければ nil を返す。
+func profiledBody(name *Symbol, ll *LambdaList, b *Cell, lambdaEnv *Env) func(*Cell, *Env) (Any, *Env) {
+	p := currentProfile.Load()
+	if p == nil {
+		return nil
+	}
+	fn := p.function(name)
+	return func(args *Cell, argsEnv *Env) (Any, *Env) {
+		env := newCallEnv(lambdaEnv, argsEnv)
+		ll.BindArgs(args, argsEnv, env)
+		fr := p.enter(fn, argsEnv, env, false)
+		defer fr.exit()
+		return evalBody(b, env), nil
+	}
+}
+
+// future の式 a を環境 env で評価する。プロファイル中ならば呼び出しを
+// (future) として数える。
+func evalFuture(a Any, env *Env) Any {
+	p := currentProfile.Load()
+	if p == nil {
+		return env.Eval(a)
+	}
+	fenv := &Env{make(map[*Symbol]Any), env, sync.Mutex{}}
+	fr := p.enter(p.future, env, fenv, true)
+	defer fr.exit()
+	return fenv.Eval(a)
+}
+
+// 関数ごとの集計
+type profileEntry struct {
+	name            string
+	calls           int64
+	inclusive, self time.Duration
+}
+
+// 関数ごとに回数と時間を集計する。
+func (p *Profile) entries() []*profileEntry {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	table := make(map[*profiledFunc]*profileEntry)
+	var walk func(node *callNode)
+	walk = func(node *callNode) {
+		if node.fn != nil {
+			e := table[node.fn]
+			if e == nil {
+				e = &profileEntry{name: node.fn.name}
+				table[node.fn] = e
+			}
+			e.calls += node.calls
+			e.self += time.Duration(node.self)
+			for n := node; n.fn != nil; n = n.parent {
+				table[n.fn].inclusive += time.Duration(node.self)
+			}
+		}
+		for _, child := range node.children {
+			walk(child)
+		}
+	}
+	walk(p.root)
+	var list []*profileEntry
+	for _, e := range table {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].self != list[j].self {
+			return list[i].self > list[j].self
+		}
+		return list[i].name < list[j].name
+	})
+	return list
+}
+
+// 関数ごとの回数と時間の一覧を自身の時間の多い順に書く。
+func (p *Profile) WriteReport(w io.Writer) {
+	fmt.Fprintf(w, "%10s %14s %14s  %s\n", "calls", "inclusive", "exclusive",
+		"function")
+	for _, e := range p.entries() {
+		fmt.Fprintf(w, "%10d %14v %14v  %s\n", e.calls, e.inclusive, e.self,
+			e.name)
+	}
+}
+
+// Go の pprof で読める形式 (gzip した protocol buffer) で書く。
+// 標本は呼び出しの木の節ごとに回数と自身の時間を値とする。
+func (p *Profile) WritePprof(w io.Writer) error {
+	p.lock.Lock()
+	var b protoBuffer
+	index := map[string]int64{"": 0}
+	table := []string{""}
+	str := func(s string) int64 {
+		i, ok := index[s]
+		if !ok {
+			i = int64(len(table))
+			index[s] = i
+			table = append(table, s)
+		}
+		return i
+	}
+	valueType := func(typ, unit string) []byte {
+		var v protoBuffer
+		v.int64Field(1, str(typ))
+		v.int64Field(2, str(unit))
+		return v.Bytes()
+	}
+	b.bytesField(1, valueType("calls", "count"))
+	b.bytesField(1, valueType("time", "nanoseconds"))
+	var walk func(node *callNode)
+	walk = func(node *callNode) {
+		if node.fn != nil {
+			var s protoBuffer
+			var ids []uint64
+			for n := node; n.fn != nil; n = n.parent {
+				ids = append(ids, n.fn.id)
+			}
+			s.packedField(1, ids)
+			s.packedField(2, []uint64{uint64(node.calls), uint64(node.self)})
+			b.bytesField(2, s.Bytes())
+		}
+		for _, child := range node.children {
+			walk(child)
+		}
+	}
+	walk(p.root)
+	funcs := []*profiledFunc{p.future}
+	for _, fn := range p.funcs {
+		funcs = append(funcs, fn)
+	}
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].id < funcs[j].id })
+	for _, fn := range funcs {
+		var line, loc protoBuffer
+		line.uint64Field(1, fn.id)
+		loc.uint64Field(1, fn.id)
+		loc.bytesField(4, line.Bytes())
+		b.bytesField(4, loc.Bytes())
+	}
+	for _, fn := range funcs {
+		var f protoBuffer
+		f.uint64Field(1, fn.id)
+		f.int64Field(2, str(fn.name))
+		f.int64Field(3, str(fn.name))
+		f.int64Field(4, str(fn.file))
+		b.bytesField(5, f.Bytes())
+	}
+	b.int64Field(9, p.start.UnixNano())
+	b.int64Field(10, int64(p.duration))
+	b.bytesField(11, valueType("calls", "count"))
+	b.int64Field(12, 1)
+	b.int64Field(14, str("time"))
+	p.lock.Unlock()
+	for _, s := range table {
+		b.bytesField(6, []byte(s))
+	}
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.Bytes()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// protocol buffer の符号化
+type protoBuffer struct {
+	bytes.Buffer
+}
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.WriteByte(byte(x) | 0x80)
+		x >>= 7
+	}
+	b.WriteByte(byte(x))
+}
+
+func (b *protoBuffer) uint64Field(tag int, x uint64) {
+	b.varint(uint64(tag) << 3)
+	b.varint(x)
+}
+
+func (b *protoBuffer) int64Field(tag int, x int64) {
+	b.uint64Field(tag, uint64(x))
+}
+
+func (b *protoBuffer) bytesField(tag int, x []byte) {
+	b.varint(uint64(tag)<<3 | 2)
+	b.varint(uint64(len(x)))
+	b.Write(x)
+}
+
+func (b *protoBuffer) packedField(tag int, x []uint64) {
+	var v protoBuffer
+	for _, u := range x {
+		v.varint(u)
+	}
+	b.bytesField(tag, v.Bytes())
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
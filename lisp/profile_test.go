@@ -0,0 +1,207 @@
+// H25.5/13 (鈴)
+
+package lisp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestProfileEntries(t *testing.T) {
+	p := &Profile{root: &callNode{}}
+	fa := &profiledFunc{id: 2, name: "a"}
+	fb := &profiledFunc{id: 3, name: "b"}
+	a := &callNode{fn: fa, parent: p.root, calls: 1, self: 10}
+	ab := &callNode{fn: fb, parent: a, calls: 2, self: 5}
+	b := &callNode{fn: fb, parent: p.root, calls: 1, self: 7}
+	p.root.children = map[*profiledFunc]*callNode{fa: a, fb: b}
+	a.children = map[*profiledFunc]*callNode{fb: ab}
+	var got []profileEntry
+	for _, e := range p.entries() {
+		got = append(got, *e)
+	}
+	want := []profileEntry{{"b", 3, 12, 12}, {"a", 1, 15, 10}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// プロファイルしながら式を評価する。
+func profileText(t *testing.T, src string) *Profile {
+	StartProfiling()
+	got := evalText(src)
+	p := StopProfiling()
+	if got != "3" {
+		t.Fatalf("%s => %s", src, got)
+	}
+	return p
+}
+
+func TestProfileCalls(t *testing.T) {
+	p := profileText(t, `(defun pf-leaf (x) x)
+	  (defun pf-rec (n) (if (= n 0) (pf-leaf 0) (pf-rec (- n 1))))
+	  (defun pf-top () (+ (pf-leaf 1) (pf-leaf 2) (pf-rec 3)))
+	  (pf-top)`)
+	entries := make(map[string]*profileEntry)
+	for _, e := range p.entries() {
+		entries[e.name] = e
+		if e.inclusive < e.self {
+			t.Errorf("%s: inclusive %v < exclusive %v", e.name, e.inclusive, e.self)
+		}
+	}
+	for name, calls := range map[string]int64{"pf-top": 1, "pf-leaf": 3, "pf-rec": 4} {
+		if e := entries[name]; e == nil || e.calls != calls {
+			t.Errorf("%s: %+v, want %d calls", name, e, calls)
+		}
+	}
+	if top := entries["pf-top"]; top != nil {
+		var self int64
+		for _, e := range entries {
+			self += int64(e.self)
+		}
+		if int64(top.inclusive) != self {
+			t.Errorf("pf-top: inclusive %v, want the sum of exclusive %v",
+				top.inclusive, self)
+		}
+	}
+}
+
+// protocol buffer の欄 (テスト用)
+type protoField struct {
+	tag   int
+	value uint64 // varint の値
+	bytes []byte // 長さ付きの値
+}
+
+// protocol buffer のメッセージを欄に分ける。
+func protoFields(t *testing.T, b []byte) []protoField {
+	t.Helper()
+	var fields []protoField
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		b = b[n:]
+		f := protoField{tag: int(key >> 3)}
+		x, n := binary.Uvarint(b)
+		b = b[n:]
+		switch key & 7 {
+		case 0:
+			f.value = x
+		case 2:
+			f.bytes, b = b[:x], b[x:]
+		default:
+			t.Fatalf("wire type %d", key&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// 詰め込んだ varint の列を読む。
+func protoPacked(b []byte) []uint64 {
+	var list []uint64
+	for len(b) > 0 {
+		x, n := binary.Uvarint(b)
+		list = append(list, x)
+		b = b[n:]
+	}
+	return list
+}
+
+func TestWritePprof(t *testing.T) {
+	p := profileText(t, `(defun pf-inner (x) x)
+	  (defun pf-outer () (+ (pf-inner 1) (pf-inner 2)))
+	  (pf-outer)`)
+	var buf bytes.Buffer
+	if err := p.WritePprof(&buf); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var strs []string
+	var samples [][]protoField
+	for _, f := range protoFields(t, data) {
+		switch f.tag {
+		case 2:
+			samples = append(samples, protoFields(t, f.bytes))
+		case 6:
+			strs = append(strs, string(f.bytes))
+		}
+	}
+	if len(strs) == 0 || strs[0] != "" {
+		t.Fatalf("string table %q", strs)
+	}
+	ids := make(map[string]uint64)
+	for _, fn := range p.funcs {
+		ids[fn.name] = fn.id
+	}
+	// 標本は関数の列 (葉から根へ) ごとの回数と自身の時間である。
+	calls := make(map[string]uint64)
+	for _, s := range samples {
+		var locs, values []uint64
+		for _, f := range s {
+			switch f.tag {
+			case 1:
+				locs = protoPacked(f.bytes)
+			case 2:
+				values = protoPacked(f.bytes)
+			}
+		}
+		if len(values) != 2 {
+			t.Fatalf("sample values %v", values)
+		}
+		switch {
+		case reflect.DeepEqual(locs, []uint64{ids["pf-outer"]}):
+			calls["pf-outer"] = values[0]
+		case reflect.DeepEqual(locs, []uint64{ids["pf-inner"], ids["pf-outer"]}):
+			calls["pf-inner"] = values[0]
+		default:
+			t.Errorf("unexpected sample %v", locs)
+		}
+	}
+	if want := map[string]uint64{"pf-outer": 1, "pf-inner": 2}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls %v, want %v", calls, want)
+	}
+	for _, s := range []string{"calls", "count", "time", "nanoseconds",
+		"pf-inner", "pf-outer", "(future)"} {
+		found := false
+		for _, x := range strs {
+			found = found || x == s
+		}
+		if !found {
+			t.Errorf("%q not in string table %q", s, strs)
+		}
+	}
+}
+
+/*
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,241 @@
+// H25.5/12 (鈴)
+
+// このファイルは対話セッションのための機能を実装する。
+// Common Lisp と同じく *, **, *** は直前の三つの結果を，+, ++, +++ は
+// 直前の三つの入力の式を表す。ただし関数と変数の名前空間が同じこの Lisp
+// では * と + は乗算と加算の関数だから，入力した式全体が * または +
+// だけのときに限りその値を返す。式の中の * と + は関数のままとする。
+// **, ***, ++, +++ は普通の大域変数である。
+// また : で始まる行のうち :load, :time, :env, :reset, :quit, :help は
+// メタコマンドとして扱う。
+
+package lisp
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 結果と入力の履歴の変数
+var multiplySymbol = NewSymbol("*")
+var addSymbol = NewSymbol("+")
+var resultSymbols = [...]*Symbol{NewSymbol("**"), NewSymbol("***")}
+var inputSymbols = [...]*Symbol{NewSymbol("++"), NewSymbol("+++")}
+
+// 直前の結果と入力の式. 参照するときは historyLock で排他すること。
+var lastResult, lastInput Any = (*Cell)(nil), (*Cell)(nil)
+var historyLock sync.Mutex
+
+// 入力の式 x がシンボル * または + だけならば直前の結果または入力の式を
+// クォートした式を返し，さもなくば x を返す。式の中の * と + は関数と
+// して使われうるから置き換えない。
+func substituteHistory(x Any) Any {
+	historyLock.Lock()
+	defer historyLock.Unlock()
+	switch x {
+	case multiplySymbol:
+		return &Cell{QuoteSymbol, &Cell{lastResult, nil}}
+	case addSymbol:
+		return &Cell{QuoteSymbol, &Cell{lastInput, nil}}
+	}
+	return x
+}
+
+// 入力の式 x と結果 y を履歴に加える。
+func recordHistory(x, y Any) {
+	historyLock.Lock()
+	defer historyLock.Unlock()
+	Globals.Set(resultSymbols[1], Globals.Get(resultSymbols[0]))
+	Globals.Set(resultSymbols[0], lastResult)
+	lastResult = y
+	Globals.Set(inputSymbols[1], Globals.Get(inputSymbols[0]))
+	Globals.Set(inputSymbols[0], lastInput)
+	lastInput = x
+}
+
+// 履歴を空にする。
+func clearHistory() {
+	historyLock.Lock()
+	defer historyLock.Unlock()
+	lastResult, lastInput = (*Cell)(nil), (*Cell)(nil)
+	for _, sym := range resultSymbols {
+		Globals.Set(sym, (*Cell)(nil))
+	}
+	for _, sym := range inputSymbols {
+		Globals.Set(sym, (*Cell)(nil))
+	}
+}
+
+// :reset で戻す大域環境の写し
+var savedGlobals map[*Symbol]Any
+
+// 現在の大域環境を :reset で戻す状態として保存する。
+func SaveGlobals() {
+	Globals.Lock.Lock()
+	defer Globals.Lock.Unlock()
+	savedGlobals = make(map[*Symbol]Any, len(Globals.Table))
+	for sym, val := range Globals.Table {
+		savedGlobals[sym] = val
+	}
+}
+
+// 大域環境を SaveGlobals で保存した状態 (保存していなければ組込みの
+// 関数と変数だけの状態) に戻し，結果と入力の履歴を空にする。
+func ResetGlobals() {
+	Globals.Lock.Lock()
+	saved := savedGlobals
+	if saved == nil {
+		saved = builtins
+	}
+	Globals.Table = make(map[*Symbol]Any, len(saved))
+	for sym, val := range saved {
+		Globals.Table[sym] = val
+	}
+	if savedGlobals == nil {
+		Globals.Table[LoadPathSymbol] = initialLoadPath()
+	}
+	Globals.Lock.Unlock()
+	clearHistory()
+}
+
+// メタコマンド
+type metaCommand struct {
+	usage string
+	doc   string
+	run   func(arg string, output io.Writer) (quit bool)
+}
+
+var metaCommands map[string]metaCommand
+
+func init() {
+	metaCommands = map[string]metaCommand{
+		":load": {":load file", "Load a Lisp source file.", loadCommand},
+		":time": {":time expr...", "Evaluate expressions and show the elapsed time.",
+			timeCommand},
+		":env":   {":env", "Show the global variables you have defined.", envCommand},
+		":reset": {":reset", "Forget your global definitions and the history.", resetCommand},
+		":quit":  {":quit", "End the session.", quitCommand},
+		":help":  {":help", "Show this list of commands.", helpCommand},
+	}
+}
+
+// 行を : で始まるコマンド名とその引数に分ける。
+func splitMetaCommand(line string) (name, arg string) {
+	line = strings.TrimSpace(line)
+	if i := strings.IndexAny(line, " \t\n"); i >= 0 {
+		return line[:i], strings.TrimSpace(line[i:])
+	}
+	return line, ""
+}
+
+// 行がメタコマンドならば true を返す。
+func IsMetaCommand(line string) bool {
+	name, _ := splitMetaCommand(line)
+	_, ok := metaCommands[name]
+	return ok
+}
+
+// メタコマンドの行を実行する。セッションを終えるべきならば true を返す。
+func MetaCommand(line string, output io.Writer) (quit bool) {
+	name, arg := splitMetaCommand(line)
+	cmd, ok := metaCommands[name]
+	if !ok {
+		panic(fmt.Errorf("unknown command: %s", name))
+	}
+	return cmd.run(arg, output)
+}
+
+func loadCommand(arg string, output io.Writer) bool {
+	if arg == "" {
+		panic(fmt.Errorf("file name expected: :load"))
+	}
+	Load(arg)
+	fmt.Fprintf(output, "; loaded %s\n", arg)
+	return false
+}
+
+func timeCommand(arg string, output io.Writer) bool {
+	start := time.Now()
+	if !ReadEvalPrint(strings.NewReader(arg), output) {
+		panic(fmt.Errorf("incomplete expression: %s", arg))
+	}
+	fmt.Fprintf(output, "; %v elapsed\n", time.Since(start))
+	return false
+}
+
+func envCommand(arg string, output io.Writer) bool {
+	Globals.Lock.Lock()
+	var syms []*Symbol
+	for sym := range Globals.Table {
+		if sym.Package != LispPackage {
+			syms = append(syms, sym)
+		}
+	}
+	Globals.Lock.Unlock()
+	names := make(map[*Symbol]string, len(syms))
+	for _, sym := range syms {
+		names[sym] = StringFor(sym)
+	}
+	sort.Slice(syms, func(i, j int) bool {
+		return names[syms[i]] < names[syms[j]]
+	})
+	for _, sym := range syms {
+		val := Globals.Get(sym)
+		if IsFunction(val) {
+			fmt.Fprintf(output, "%s: %s\n", names[sym], typeNameFor(val))
+		} else {
+			fmt.Fprintf(output, "%s = %s\n", names[sym], StringFor(val))
+		}
+	}
+	return false
+}
+
+func resetCommand(arg string, output io.Writer) bool {
+	ResetGlobals()
+	fmt.Fprintln(output, "; reset")
+	return false
+}
+
+func quitCommand(arg string, output io.Writer) bool {
+	return true
+}
+
+func helpCommand(arg string, output io.Writer) bool {
+	var names []string
+	for name := range metaCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cmd := metaCommands[name]
+		fmt.Fprintf(output, "%-14s %s\n", cmd.usage, cmd.doc)
+	}
+	fmt.Fprintln(output, "*, **, *** hold the last results; +, ++, +++ the last inputs.")
+	return false
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,86 @@
+// H25.5/13 (鈴)
+
+package lisp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadEvalPrintHistory(t *testing.T) {
+	clearHistory()
+	var out strings.Builder
+	ReadEvalPrint(strings.NewReader(`
+(+ 1 2)
+(* 4 5)
+*
+**
++
+(list ** +++)
+(defun sum (l) (apply + l))
+(sum '(4 5))
+(apply * '(2 3))
+((lambda (f) (f 2 3)) +)
+`), &out)
+	want := `(+ 1 2) => 3
+(* 4 5) => 20
+* => 20
+** => 20
++ => **
+(list ** +++) => (20 *)
+(defun sum (l) (apply + l)) => sum
+(sum '(4 5)) => 9
+(apply * '(2 3)) => 6
+((lambda (f) (f 2 3)) +) => 5
+`
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMetaCommand(t *testing.T) {
+	for _, c := range []struct {
+		line string
+		ok   bool
+	}{
+		{":help", true}, {" :time (+ 1 2)", true}, {":env", true},
+		{":foo", false}, {":quit now", true}, {"(+ 1 2)", false},
+	} {
+		if ok := IsMetaCommand(c.line); ok != c.ok {
+			t.Errorf("IsMetaCommand(%q) = %v", c.line, ok)
+		}
+	}
+	var out strings.Builder
+	if MetaCommand(":time (+ 40 2)", &out) {
+		t.Error(":time quits")
+	}
+	if got := out.String(); !strings.HasPrefix(got, "(+ 40 2) => 42\n; ") ||
+		!strings.HasSuffix(got, " elapsed\n") {
+		t.Errorf(":time printed %q", got)
+	}
+	if !MetaCommand(":quit", &out) {
+		t.Error(":quit does not quit")
+	}
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,424 @@
+// H25.5/5 (鈴)
+
+// このファイルは入出力のストリームとファイル入出力の組込み関数を実装する。
+// ストリームは io.Reader または io.Writer を包む。出力用のストリームは
+// io.Writer を実装するから format の出力先にもなる。
+// print などの出力先を省略した出力は変数 *standard-output* の値の
+// ストリームへ，read-line などは *standard-input* の値のストリーム
+// から行う。
+
+package lisp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/scanner"
+	"unicode/utf8"
+)
+
+// 入出力のストリーム
+type Stream struct {
+	Name   string
+	reader *bufio.Reader    // 入力用ならば非 nil
+	writer io.Writer        // 出力用ならば非 nil
+	buffer *strings.Builder // 文字列への出力用ならば非 nil
+	closer io.Closer        // ファイルならば非 nil
+	lock   sync.Mutex
+}
+
+// 標準入出力の変数
+var StandardOutputSymbol = NewSymbol("*standard-output*")
+var StandardInputSymbol = NewSymbol("*standard-input*")
+
+// 標準入力のストリーム (*standard-input* の初期値)
+var stdinStream = NewInputStream("stdin", os.Stdin)
+
+// 標準入力のストリームが読むバッファ付きの入力を返す。標準入力を直接
+// 読む行エディタなどと共有すれば，先に入力された文字を互いに失わない。
+func StdinReader() *bufio.Reader {
+	return stdinStream.reader
+}
+
+// 入力を読むストリームを作る。
+func NewInputStream(name string, r io.Reader) *Stream {
+	return &Stream{Name: name, reader: bufio.NewReader(r)}
+}
+
+// 出力を書くストリームを作る。
+func NewOutputStream(name string, w io.Writer) *Stream {
+	return &Stream{Name: name, writer: w}
+}
+
+// 文字列へ出力するストリームを作る。書いた内容は Contents で得られる。
+func NewStringOutputStream() *Stream {
+	var b strings.Builder
+	return &Stream{Name: "string", writer: &b, buffer: &b}
+}
+
+// ストリームの文字列表現 (表示用)
+func (s *Stream) String() string {
+	return "#<stream " + s.Name + ">"
+}
+
+// ストリームへ書く。io.Writer を実装する。
+func (s *Stream) Write(p []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.writer == nil {
+		panic(fmt.Errorf("not an output stream: %s", s.Name))
+	}
+	return s.writer.Write(p)
+}
+
+// 文字列へ出力するストリームならば，それまでに書いた内容を返す。
+func (s *Stream) Contents() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.buffer == nil {
+		panic(fmt.Errorf("not a string stream: %s", s.Name))
+	}
+	return s.buffer.String()
+}
+
+// 一行を読む。行末の改行は含めない。EOF ならば false を返す。
+func (s *Stream) ReadLine() (string, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	line, err := s.input().ReadString('\n')
+	if err == io.EOF && line == "" {
+		return "", false
+	} else if err != nil && err != io.EOF {
+		panic(err)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), true
+}
+
+// 一文字を読む。EOF ならば false を返す。
+func (s *Stream) ReadChar() (rune, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	r, _, err := s.input().ReadRune()
+	if err == io.EOF {
+		return 0, false
+	} else if err != nil {
+		panic(err)
+	}
+	return r, true
+}
+
+// Lisp 式を一つ読む。EOF ならば false を返す。
+// 式が途中で終わっていればパニックする。
+// 字句解析器が式の直後に先読みした一文字は入力に戻すから，続けて
+// read-char や read-line でその文字から読める。
+func (s *Stream) Read() (Any, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	r := s.input()
+	lex := NewLazyLex(runeReader{r})
+	if lex.AtEOF() {
+		return nil, false
+	}
+	x := lex.Read()
+	if x == nil {
+		panic(fmt.Errorf("incomplete expression: %s", s.Name))
+	}
+	if lex.Peek() != scanner.EOF {
+		r.UnreadRune() // 最後に読んだ文字が先読みした文字である。
+	}
+	return x, true
+}
+
+// 一度に一文字ずつ読む io.Reader.
+// 字句解析器が式の直後の一文字より先まで読み込まないようにする。
+// 字句解析器は読み込み先に少なくとも utf8.UTFMax バイトの余地を与える。
+type runeReader struct {
+	r *bufio.Reader
+}
+
+func (rr runeReader) Read(p []byte) (int, error) {
+	c, size, err := rr.r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if c == utf8.RuneError && size == 1 { // 不正なバイトはそのまま渡す。
+		rr.r.UnreadRune()
+		b, _ := rr.r.ReadByte()
+		p[0] = b
+		return 1, nil
+	}
+	return utf8.EncodeRune(p, c), nil
+}
+
+func (s *Stream) input() *bufio.Reader {
+	if s.reader == nil {
+		panic(fmt.Errorf("not an input stream: %s", s.Name))
+	}
+	return s.reader
+}
+
+// ストリームを閉じる。出力のバッファは書き出す。
+func (s *Stream) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if w, ok := s.writer.(*bufio.Writer); ok {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if s.closer != nil {
+		err := s.closer.Close()
+		s.closer = nil
+		return err
+	}
+	return nil
+}
+
+// 入力用にファイルを開く。
+func OpenInputFile(name string) *Stream {
+	file, err := os.Open(name)
+	if err != nil {
+		panic(err)
+	}
+	s := NewInputStream(name, file)
+	s.closer = file
+	return s
+}
+
+// 出力用にファイルを開く。ファイルがあれば切り詰める。
+// 閉じずに終わっても書いた内容を失わないように，バッファを介さずに書く。
+func OpenOutputFile(name string) *Stream {
+	file, err := os.Create(name)
+	if err != nil {
+		panic(err)
+	}
+	s := NewOutputStream(name, file)
+	s.closer = file
+	return s
+}
+
+// 変数 sym の値であるストリームを返す。
+func streamVariable(sym *Symbol) *Stream {
+	v, _ := Globals.Lookup(sym)
+	s, ok := v.(*Stream)
+	if !ok {
+		panic(fmt.Errorf("%s is not a stream: %s", sym.string, StringFor(v)))
+	}
+	return s
+}
+
+// 大域的な *standard-output* の値のストリームを返す。
+func StandardOutput() *Stream {
+	return streamVariable(StandardOutputSymbol)
+}
+
+// with-output-to-string が束縛した出力先を環境に記録するための
+// (intern されない) シンボル. 値は *Stream であり，nil ならば束縛が
+// 無い (大域的な *standard-output* の値へ出力する) ことを表す。
+// 関数の呼び出しの環境には呼び出し元の値を，future を評価する環境には
+// nil を束縛するから，束縛は式の評価の間だけ動的に及ぶ。
+var outputSymbol = &Symbol{string: "#<output>"}
+
+// 環境で with-output-to-string が束縛した出力先を返す。
+// 無ければ nil を返す。
+func (env *Env) boundOutput() *Stream {
+	if v, ok := env.Lookup(outputSymbol); ok {
+		return v.(*Stream)
+	}
+	return nil
+}
+
+// 環境での *standard-output* の値のストリームを返す。
+func standardOutput(env *Env) *Stream {
+	if slowPath() {
+		if s := env.boundOutput(); s != nil {
+			return s
+		}
+	}
+	return StandardOutput()
+}
+
+// 省略可能な最後の引数 a[i] を出力先として得る。
+// 省略されているか t ならば環境での標準出力とする。
+func outputArg(a []Any, i int, env *Env) *Stream {
+	if len(a) > i+1 {
+		CheckArity(i+1, a)
+	}
+	if i >= len(a) || a[i] == TSymbol {
+		return standardOutput(env)
+	}
+	return streamArg(a[i])
+}
+
+// 省略可能な最後の引数 a[i] を入力元として得る。
+// 省略されているか t ならば標準入力とする。
+func inputArg(a []Any, i int) *Stream {
+	if len(a) > i+1 {
+		CheckArity(i+1, a)
+	}
+	if i >= len(a) || a[i] == TSymbol {
+		return streamVariable(StandardInputSymbol)
+	}
+	return streamArg(a[i])
+}
+
+// 引数をストリームとして得る。
+func streamArg(x Any) *Stream {
+	s, ok := x.(*Stream)
+	if !ok {
+		panic(fmt.Errorf("stream expected: %s", StringFor(x)))
+	}
+	return s
+}
+
+// (open-input-file filename)
+func openInputFileFunc(a []Any) Any {
+	CheckArity(1, a)
+	return OpenInputFile(a[0].(string))
+}
+
+// (open-output-file filename)
+func openOutputFileFunc(a []Any) Any {
+	CheckArity(1, a)
+	return OpenOutputFile(a[0].(string))
+}
+
+// (close stream)
+func closeFunc(a []Any) Any {
+	CheckArity(1, a)
+	if err := streamArg(a[0]).Close(); err != nil {
+		panic(err)
+	}
+	return TSymbol
+}
+
+// (read-line [stream]) EOF ならば nil を返す。
+func readLineFunc(a []Any) Any {
+	if line, ok := inputArg(a, 0).ReadLine(); ok {
+		return line
+	}
+	return (*Cell)(nil)
+}
+
+// (read-char [stream]) 一文字の文字列を返す。EOF ならば nil を返す。
+func readCharFunc(a []Any) Any {
+	if r, ok := inputArg(a, 0).ReadChar(); ok {
+		return string(r)
+	}
+	return (*Cell)(nil)
+}
+
+// (write-string string [stream])
+func writeStringFunc(a []Any, env *Env) Any {
+	CheckArity(-1, a)
+	io.WriteString(outputArg(a, 1, env), a[0].(string))
+	return a[0]
+}
+
+// (write object [stream]) 読み込める形式で書く。
+func writeFunc(a []Any, env *Env) Any {
+	CheckArity(-1, a)
+	io.WriteString(outputArg(a, 1, env), StringFor(a[0]))
+	return a[0]
+}
+
+// (display object [stream]) 文字列は引用符を付けずに書く。
+func displayFunc(a []Any, env *Env) Any {
+	CheckArity(-1, a)
+	io.WriteString(outputArg(a, 1, env), aestheticStringFor(a[0]))
+	return a[0]
+}
+
+// (newline [stream])
+func newlineFunc(a []Any, env *Env) Any {
+	io.WriteString(outputArg(a, 0, env), "\n")
+	return (*Cell)(nil)
+}
+
+// (with-open-file (var filename [:direction :input|:output]) expression...)
+// ファイルを開いて var に束縛し，式を評価した後にファイルを閉じる。
+func withOpenFileForm(x *Cell, env *Env) (Any, *Env) {
+	a, b := CheckForUnaryAndRest(x)
+	spec := a.(*Cell)
+	v, c := CheckForUnaryAndRest(spec)
+	name := env.Eval(c.Car).(string)
+	var s *Stream
+	switch direction := evalArgs(c.Cdr, env); {
+	case len(direction) == 0 || len(direction) == 2 &&
+		direction[0] == Keyword("direction") &&
+		direction[1] == Keyword("input"):
+		s = OpenInputFile(name)
+	case len(direction) == 2 && direction[0] == Keyword("direction") &&
+		direction[1] == Keyword("output"):
+		s = OpenOutputFile(name)
+	default:
+		panic(fmt.Errorf(":direction :input or :output expected: %s",
+			StringFor(c.Cdr)))
+	}
+	closed := false
+	defer func() {
+		if !closed { // 評価中のパニックを優先して閉じるエラーは捨てる。
+			s.Close()
+		}
+	}()
+	table := map[*Symbol]Any{v.(*Symbol): s}
+	val := evalBody(b, &Env{table, env, sync.Mutex{}})
+	closed = true
+	if err := s.Close(); err != nil {
+		panic(err)
+	}
+	return val, nil
+}
+
+// (with-output-to-string ([var]) expression...)
+// 文字列へ出力するストリームを var に束縛し，かつ式の評価の間は
+// *standard-output* の値とする。future で並行に評価する式の出力には
+// 影響しない。式の評価後に出力した内容を返す。
+func withOutputToStringForm(x *Cell, env *Env) (Any, *Env) {
+	a, b := CheckForUnaryAndRest(x)
+	s := NewStringOutputStream()
+	table := map[*Symbol]Any{outputSymbol: s}
+	if vars := a.(*Cell); vars != nil {
+		table[vars.Car.(*Symbol)] = s
+	}
+	body := &Env{table, env, sync.Mutex{}}
+	defer beginSlowPath()()
+	// 本体で作った関数を後で呼び出しても出力先が及ばないように解く。
+	defer body.define(outputSymbol, (*Stream)(nil))
+	evalBody(b, body)
+	return s.Contents(), nil
+}
+
+// 式の並びを評価し最後の値を返す。末尾式も評価し終える。
+func evalBody(x *Cell, env *Env) Any {
+	a, e := prognForm(x, env)
+	if e != nil {
+		return e.Eval(a)
+	}
+	return a
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,110 @@
+// H25.5/13 (鈴)
+
+package lisp
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestStreamReadThenReadChar(t *testing.T) {
+	s := NewInputStream("test", strings.NewReader("(a b)cd\nfoo bar\nλx y\n"))
+	check := func(what, got, want string) {
+		t.Helper()
+		if got != want {
+			t.Errorf("%s => %q, want %q", what, got, want)
+		}
+	}
+	read := func() string {
+		x, ok := s.Read()
+		if !ok {
+			return "EOF"
+		}
+		return StringFor(x)
+	}
+	check("read", read(), "(a b)")
+	c, _ := s.ReadChar()
+	check("read-char", string(c), "c")
+	line, _ := s.ReadLine()
+	check("read-line", line, "d")
+	check("read", read(), "foo")
+	line, _ = s.ReadLine()
+	check("read-line", line, " bar")
+	check("read", read(), "λx")
+	c, _ = s.ReadChar()
+	check("read-char", string(c), " ")
+	check("read", read(), "y")
+	check("read", read(), "EOF")
+}
+
+func TestReadAndEval(t *testing.T) {
+	checkEval(t, []evalCase{
+		{`(read-from-string "(1 2) 3")`, "(1 2)"},
+		{`(read "foo")`, "foo"},
+		{`(read-from-string "" nil 'eof)`, "eof"},
+		{`(read-from-string "(1")`, "error: incomplete expression: string"},
+		{"(eval '(+ 1 2))", "3"},
+		{"(let ((x 5)) (eval '(* x 2) (the-environment)))", "10"},
+		{"(the-environment no-such-var (car 1))", "error: arity 0; given 2"},
+	})
+}
+
+func TestWithOutputToString(t *testing.T) {
+	checkEval(t, []evalCase{
+		{`(defun wots-out (x) (write-string x *standard-output*) (display x))
+		  (with-output-to-string ()
+		    (wots-out "a")
+		    (with-output-to-string (s) (wots-out "b"))
+		    (display 1/2))`, `"aa1/2"`},
+		{`(setq wots-future nil)
+		  (setq wots-body nil)
+		  (with-output-to-string ()
+		    (setq wots-future (force (future *standard-output*)))
+		    (setq wots-body *standard-output*))
+		  (list (eq wots-future wots-body) (eq wots-future *standard-output*))`,
+			"(() t)"},
+		{`(setq wots-late nil)
+		  (with-output-to-string ()
+		    (setq wots-late (lambda () (display "late"))))
+		  (with-output-to-string () (wots-late))`, `"late"`},
+		{`(with-output-to-string () (write-string "a" t) (newline t))`,
+			`"a\n"`},
+		{`(write-string "a" 1)`, "error: stream expected: 1"},
+		{"(read-line 'foo)", "error: stream expected: foo"},
+	})
+}
+
+func TestOutputFileWithoutClose(t *testing.T) {
+	name := strconv.Quote(filepath.Join(t.TempDir(), "out.txt"))
+	checkEval(t, []evalCase{
+		{"(write-string \"kept\" (open-output-file " + name + "))", `"kept"`},
+		{"(read-line (open-input-file " + name + "))", `"kept"`},
+		{"(with-open-file (in " + name + ") (read-line 5))",
+			"error: stream expected: 5"},
+		{"(with-open-file (in " + name + ") (read-line in))", `"kept"`},
+	})
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
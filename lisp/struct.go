@@ -0,0 +1,205 @@
+// H25.4/20 (鈴)
+
+// このファイルは defstruct による構造体を実装する。
+
+package lisp
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// 構造体の型. 型名とフィールド名と各フィールドの既定値の式からなる。
+type StructType struct {
+	Name     *Symbol
+	Fields   []*Symbol
+	Defaults []Any
+	Env      *Env        // 既定値の式を評価する環境
+	params   *LambdaList // キーワード引数をとる構築子の仮引数リスト
+	optional *LambdaList // 位置で引数をとる構築子の仮引数リスト
+}
+
+// 構造体の値
+type Struct struct {
+	Type   *StructType
+	Fields []Any
+}
+
+var structTypes = make(map[*Symbol]*StructType)
+var structLock sync.Mutex
+
+// 型名に対する構造体の型を得る。無ければパニックする。
+func LookupStructType(name *Symbol) *StructType {
+	structLock.Lock()
+	st, ok := structTypes[name]
+	structLock.Unlock()
+	if !ok {
+		panic(fmt.Errorf("undefined struct: %s", name.string))
+	}
+	return st
+}
+
+// フィールド名に対する添字を返す。無ければ -1 を返す。
+func (st *StructType) FieldIndex(field *Symbol) int {
+	for i, f := range st.Fields {
+		if f == field {
+			return i
+		}
+	}
+	return -1
+}
+
+// 構造体の文字列表現 #S(型名 :フィールド 値...) を返す。
+// 各フィールドの値は elem で文字列にする。
+func stringForStruct(x *Struct, elem func(Any) string) string {
+	s := make([]string, 0, 1+2*len(x.Fields))
+	s = append(s, x.Type.Name.string)
+	for i, f := range x.Type.Fields {
+		s = append(s, ":"+f.string, elem(x.Fields[i]))
+	}
+	return "#S(" + strings.Join(s, " ") + ")"
+}
+
+// (defstruct name [field|(field default-expression)]...)
+// キーワード引数または位置による引数をとる構築子 make-name, 型述語 name-p,
+// 各フィールドの参照関数 name-field と更新関数 set-name-field を定義する。
+func defstructForm(x *Cell, env *Env) (Any, *Env) {
+	a, b := CheckForUnaryAndRest(x)
+	name, ok := a.(*Symbol)
+	if !ok {
+		panic(fmt.Errorf("struct name expected: %s", StringFor(a)))
+	}
+	st := &StructType{name, nil, nil, env, nil, nil}
+	for ; b != nil; b = b.Cdr {
+		switch f := b.Car.(type) {
+		case *Symbol:
+			st.Fields = append(st.Fields, f)
+			st.Defaults = append(st.Defaults, (*Cell)(nil))
+		case *Cell:
+			field, exp := CheckForBinary(f)
+			st.Fields = append(st.Fields, field.(*Symbol))
+			st.Defaults = append(st.Defaults, exp)
+		default:
+			panic(fmt.Errorf("symbol or (symbol expession) expected: %s",
+				StringFor(f)))
+		}
+	}
+	structLock.Lock()
+	structTypes[name] = st
+	structLock.Unlock()
+	prefix := name.string + "-"
+	env.Set(Intern("make-"+name.string), st.makeConstructor())
+	env.Set(Intern(prefix+"p"), func(a []Any) Any {
+		CheckArity(1, a)
+		s, ok := a[0].(*Struct)
+		return LispBool(ok && s.Type == st)
+	})
+	for i, f := range st.Fields {
+		env.Set(Intern(prefix+f.string), st.makeAccessor(i))
+		env.Set(Intern("set-"+prefix+f.string), st.makeSetter(i))
+	}
+	return name, nil
+}
+
+// 構築子を作る。各フィールドの値をキーワード引数 :フィールド名 で与え，
+// 省略時は既定値の式を評価する。つまり (&key (field default)...) を
+// 仮引数リストとする関数と同じように振る舞う。
+// 最初の引数がキーワードでなければ，値をフィールドの順に位置で
+// 与えたものとし (&optional (field default)...) のように振る舞う。
+func (st *StructType) makeConstructor() func([]Any) Any {
+	var params *Cell
+	for i := len(st.Fields) - 1; i >= 0; i-- {
+		params = Cons(Cons(st.Fields[i], Cons(st.Defaults[i], nil)), params)
+	}
+	st.params = ParseLambdaList(Cons(AmpKeySymbol, params))
+	st.optional = ParseLambdaList(Cons(AmpOptionalSymbol, params))
+	return func(a []Any) Any {
+		if len(a) > 0 && !isKeyword(a[0]) {
+			return st.bind(st.optional, a)
+		}
+		return st.construct(a)
+	}
+}
+
+// 引数がキーワードならば true を返す。
+func isKeyword(a Any) bool {
+	k, ok := a.(*Symbol)
+	return ok && k.IsKeyword()
+}
+
+// キーワード引数の並びから構造体を作る。
+func (st *StructType) construct(a []Any) *Struct {
+	return st.bind(st.params, a)
+}
+
+// 引数の並びを仮引数リスト ll に束縛して構造体を作る。
+func (st *StructType) bind(ll *LambdaList, a []Any) *Struct {
+	env := &Env{make(map[*Symbol]Any), st.Env, sync.Mutex{}}
+	ll.Bind(a, env)
+	fields := make([]Any, len(st.Fields))
+	for i, f := range st.Fields {
+		fields[i] = env.Table[f]
+	}
+	return &Struct{st, fields}
+}
+
+func (st *StructType) makeAccessor(i int) func([]Any) Any {
+	return func(a []Any) Any {
+		CheckArity(1, a)
+		return st.check(a[0]).Fields[i]
+	}
+}
+
+func (st *StructType) makeSetter(i int) func([]Any) Any {
+	return func(a []Any) Any {
+		CheckArity(2, a)
+		st.check(a[0]).Fields[i] = a[1]
+		return a[1]
+	}
+}
+
+// 引数がこの型の構造体か確かめて返す。
+func (st *StructType) check(a Any) *Struct {
+	if s, ok := a.(*Struct); ok && s.Type == st {
+		return s
+	}
+	panic(fmt.Errorf("%s expected: %s", st.Name.string, StringFor(a)))
+}
+
+// #S(型名 :フィールド 値...) の本体のリストから構造体を作る。
+// 各値は評価せずにそのまま使う。
+func structFromList(x *Cell) *Struct {
+	a, b := CheckForUnaryAndRest(x)
+	name, ok := a.(*Symbol)
+	if !ok {
+		panic(fmt.Errorf("struct name expected: %s", StringFor(a)))
+	}
+	args := make([]Any, 0, 4)
+	for ; b != nil; b = b.Cdr {
+		args = append(args, b.Car)
+	}
+	return LookupStructType(name).construct(args)
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
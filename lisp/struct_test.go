@@ -0,0 +1,74 @@
+// H25.5/13 (鈴)
+
+package lisp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStructConstructor(t *testing.T) {
+	checkEval(t, []evalCase{
+		{"(defstruct pos x (y 0) (z (list x y))) (make-pos 1 2)",
+			"#S(pos :x 1 :y 2 :z (1 2))"},
+		{"(make-pos 1)", "#S(pos :x 1 :y 0 :z (1 0))"},
+		{"(make-pos :y 5 :x 1)", "#S(pos :x 1 :y 5 :z (1 5))"},
+		{"(make-pos)", "#S(pos :x () :y 0 :z (() 0))"},
+		{"(make-pos :z 1 :x 2)", "#S(pos :x 2 :y 0 :z 1)"},
+		{"(make-pos :q 1 :x 2)",
+			"error: unknown keyword :q for (&key (x ()) (y 0) (z (list x y))); given (:q 1 :x 2)"},
+		{"(make-pos :q)",
+			"error: odd number of keyword arguments for (&key (x ()) (y 0) (z (list x y))); given (:q)"},
+		{"(pos-z (make-pos 1 2 3))", "3"},
+		{"(make-pos 1 2 3 4)",
+			"error: arity (&optional (x ()) (y 0) (z (list x y))); given (1 2 3 4)"},
+	})
+}
+
+func TestStructSelfReference(t *testing.T) {
+	evalText(`(defstruct cyc next val)
+	          (setq cyc (make-cyc :val 1))
+	          (set-cyc-next cyc cyc)`)
+	s := evalText("cyc")
+	if !strings.HasPrefix(s, "#S(cyc :next #S(cyc :next ") ||
+		!strings.Contains(s, "...") {
+		t.Errorf("cyc => %s", s)
+	}
+	if p := PrettyStringFor(Globals.Get(Intern("cyc")), 40); p != s {
+		t.Errorf("pprint cyc => %s, want %s", p, s)
+	}
+	evalText("(set-cyc-val cyc (list cyc 2))")
+	if s := evalText("cyc"); !strings.Contains(s, "...") {
+		t.Errorf("cyc => %s", s)
+	}
+	defer evalText("(setq *print-circle* nil)")
+	checkEval(t, []evalCase{
+		{"(setq *print-circle* t) cyc", "#1=#S(cyc :next #1# :val (#1# 2))"},
+	})
+	if p := PrettyStringFor(Globals.Get(Intern("cyc")), 10); p !=
+		"#1=#S(cyc :next #1# :val (#1# 2))" {
+		t.Errorf("pprint cyc => %s", p)
+	}
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
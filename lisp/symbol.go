@@ -0,0 +1,226 @@
+// H25.5/9 (鈴)
+
+// このファイルはシンボルの属性リストと説明文字列と，シンボルの
+// トップレベルの値を扱う組込み関数を実装する。
+// この Lisp では関数と変数は同じ名前空間にあるから，symbol-function は
+// 値が関数であることを確かめるほかは symbol-value と同じである。
+
+package lisp
+
+import (
+	"fmt"
+	"sync"
+)
+
+// 全シンボルの属性リストと説明文字列と仮引数リストを排他する。
+var symbolLock sync.Mutex
+
+// 属性 indicator の値を返す。無ければ論理値に false を返す。
+func (sym *Symbol) Get(indicator Any) (Any, bool) {
+	symbolLock.Lock()
+	defer symbolLock.Unlock()
+	for x := sym.plist; x != nil; x = x.Cdr.Cdr {
+		if x.Car == indicator {
+			return x.Cdr.Car, true
+		}
+	}
+	return nil, false
+}
+
+// 属性 indicator の値をセットする。
+func (sym *Symbol) Put(indicator Any, val Any) {
+	symbolLock.Lock()
+	defer symbolLock.Unlock()
+	for x := sym.plist; x != nil; x = x.Cdr.Cdr {
+		if x.Car == indicator {
+			x.Cdr.Car = val
+			return
+		}
+	}
+	sym.plist = Cons(indicator, Cons(val, sym.plist))
+}
+
+// 属性 indicator を取り除く。あったならば true を返す。
+func (sym *Symbol) Remove(indicator Any) bool {
+	symbolLock.Lock()
+	defer symbolLock.Unlock()
+	var prev *Cell
+	for x := sym.plist; x != nil; x = x.Cdr.Cdr {
+		if x.Car == indicator {
+			if prev == nil {
+				sym.plist = x.Cdr.Cdr
+			} else {
+				prev.Cdr.Cdr = x.Cdr.Cdr
+			}
+			return true
+		}
+		prev = x
+	}
+	return false
+}
+
+// 属性リスト (indicator value ...) の複製を返す。
+func (sym *Symbol) Plist() *Cell {
+	symbolLock.Lock()
+	defer symbolLock.Unlock()
+	var s []Any
+	for x := sym.plist; x != nil; x = x.Cdr {
+		s = append(s, x.Car)
+	}
+	return listFunc(s).(*Cell)
+}
+
+// 説明文字列を返す。無ければ "" を返す。
+func (sym *Symbol) Documentation() string {
+	symbolLock.Lock()
+	defer symbolLock.Unlock()
+	return sym.doc
+}
+
+// 説明文字列をセットする。
+func (sym *Symbol) SetDocumentation(doc string) {
+	symbolLock.Lock()
+	defer symbolLock.Unlock()
+	sym.doc = doc
+}
+
+// defun で定義した関数の仮引数リストを返す。無ければ nil を返す。
+func (sym *Symbol) lambdaList() *LambdaList {
+	symbolLock.Lock()
+	defer symbolLock.Unlock()
+	return sym.lambda
+}
+
+// defun で定義した関数の仮引数リストをセットする。
+func (sym *Symbol) setLambdaList(ll *LambdaList) {
+	symbolLock.Lock()
+	defer symbolLock.Unlock()
+	sym.lambda = ll
+}
+
+// 関数 (スペシャル・フォームを含む) ならば true を返す。
+func IsFunction(a Any) bool {
+	switch a.(type) {
+	case func(*Cell, *Env) (Any, *Env), func([]Any) Any, func([]Any, *Env) Any:
+		return true
+	}
+	return false
+}
+
+// (get symbol indicator [default])
+func getFunc(a []Any) Any {
+	CheckArity(-2, a)
+	if len(a) > 3 {
+		CheckArity(3, a)
+	}
+	if val, ok := a[0].(*Symbol).Get(a[1]); ok {
+		return val
+	} else if len(a) == 3 {
+		return a[2]
+	}
+	return (*Cell)(nil)
+}
+
+// (put symbol indicator value)
+func putFunc(a []Any) Any {
+	CheckArity(3, a)
+	a[0].(*Symbol).Put(a[1], a[2])
+	return a[2]
+}
+
+// (remprop symbol indicator)
+func rempropFunc(a []Any) Any {
+	CheckArity(2, a)
+	return LispBool(a[0].(*Symbol).Remove(a[1]))
+}
+
+// (symbol-plist symbol)
+func symbolPlistFunc(a []Any) Any {
+	CheckArity(1, a)
+	return a[0].(*Symbol).Plist()
+}
+
+// (symbol-value symbol) トップレベルの値を返す。
+func symbolValueFunc(a []Any) Any {
+	CheckArity(1, a)
+	sym := a[0].(*Symbol)
+	if sym.IsKeyword() {
+		return sym
+	}
+	return Globals.Get(sym)
+}
+
+// (symbol-function symbol)
+func symbolFunctionFunc(a []Any) Any {
+	CheckArity(1, a)
+	sym := a[0].(*Symbol)
+	if val, ok := Globals.Lookup(sym); ok && IsFunction(val) {
+		return val
+	}
+	panic(fmt.Errorf("undefined function: %s", sym.string))
+}
+
+// (boundp symbol)
+func boundpFunc(a []Any) Any {
+	CheckArity(1, a)
+	sym := a[0].(*Symbol)
+	_, ok := Globals.Lookup(sym)
+	return LispBool(ok || sym.IsKeyword())
+}
+
+// (fboundp symbol)
+func fboundpFunc(a []Any) Any {
+	CheckArity(1, a)
+	val, ok := Globals.Lookup(a[0].(*Symbol))
+	return LispBool(ok && IsFunction(val))
+}
+
+// (makunbound symbol) トップレベルの値を取り除く。
+func makunboundFunc(a []Any) Any {
+	CheckArity(1, a)
+	sym := a[0].(*Symbol)
+	Globals.undefine(sym)
+	return sym
+}
+
+// (documentation symbol) 無ければ nil を返す。
+func documentationFunc(a []Any) Any {
+	CheckArity(1, a)
+	if doc := a[0].(*Symbol).Documentation(); doc != "" {
+		return doc
+	}
+	return (*Cell)(nil)
+}
+
+// (set-documentation symbol string)
+func setDocumentationFunc(a []Any) Any {
+	CheckArity(2, a)
+	doc, ok := a[1].(string)
+	if !ok {
+		panic(fmt.Errorf("string expected: %s", StringFor(a[1])))
+	}
+	a[0].(*Symbol).SetDocumentation(doc)
+	return doc
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,62 @@
+// H25.5/13 (鈴)
+
+package lisp
+
+import "testing"
+
+func TestPlist(t *testing.T) {
+	checkEval(t, []evalCase{
+		{"(put 'sv-a 'color 'red)", "red"},
+		{"(put 'sv-a 'size 3)", "3"},
+		{"(symbol-plist 'sv-a)", "(size 3 color red)"},
+		{"(get 'sv-a 'color)", "red"},
+		{"(put 'sv-a 'color 'blue) (get 'sv-a 'color)", "blue"},
+		{"(remprop 'sv-a 'color)", "t"},
+		{"(remprop 'sv-a 'color)", "()"},
+		{"(symbol-plist 'sv-a)", "(size 3)"},
+		{"(get 'sv-a 'none)", "()"},
+	})
+}
+
+func TestSymbolValue(t *testing.T) {
+	checkEval(t, []evalCase{
+		{"(setq sv-v 5) (symbol-value 'sv-v)", "5"},
+		{"(list (boundp 'sv-v) (fboundp 'sv-v))", "(t ())"},
+		{"(makunbound 'sv-v)", "sv-v"},
+		{"(boundp 'sv-v)", "()"},
+		{"(symbol-value 'sv-v)", "error: unbound symbol: sv-v"},
+		{"(defun sv-sq (x) (* x x)) ((symbol-function 'sv-sq) 3)", "9"},
+		{"(fboundp 'car)", "t"},
+	})
+}
+
+func TestDocumentation(t *testing.T) {
+	checkEval(t, []evalCase{
+		{`(set-documentation 'sv-w "A var.")`, `"A var."`},
+		{"(documentation 'sv-w)", `"A var."`},
+		{"(documentation 'sv-none)", "()"},
+		{"(set-documentation 'sv-w 1)", "error: string expected: 1"},
+	})
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
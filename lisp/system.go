@@ -0,0 +1,68 @@
+// H25.5/12 (鈴)
+
+// このファイルはプロセスの終了と環境変数の関数を実装する。
+
+package lisp
+
+import (
+	"fmt"
+	"os"
+)
+
+// (exit) で呼ぶ関数. 終了コードを引数とする。
+// プログラムは終了の前に後始末をするためにこれを置き換えてもよい。
+var ExitHook = os.Exit
+
+// (exit [code])
+// 終了コード code (省略時は 0) でプロセスを終える。
+func exitFunc(a []Any) Any {
+	if len(a) > 1 {
+		CheckArity(1, a)
+	}
+	code := 0
+	if len(a) == 1 {
+		n, ok := a[0].(int32)
+		if !ok {
+			panic(fmt.Errorf("exit code expected: %s", StringFor(a[0])))
+		}
+		code = int(n)
+	}
+	ExitHook(code)
+	return (*Cell)(nil)
+}
+
+// (getenv name)
+// 環境変数 name の値の文字列を返す。無ければ nil を返す。
+func getenvFunc(a []Any) Any {
+	CheckArity(1, a)
+	name, ok := a[0].(string)
+	if !ok {
+		panic(fmt.Errorf("string expected: %s", StringFor(a[0])))
+	}
+	if val, ok := os.LookupEnv(name); ok {
+		return val
+	}
+	return (*Cell)(nil)
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,77 @@
+// H25.5/13 (鈴)
+
+package lisp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExit(t *testing.T) {
+	var codes []int
+	saved := ExitHook
+	ExitHook = func(code int) { codes = append(codes, code) }
+	defer func() { ExitHook = saved }()
+	checkEval(t, []evalCase{
+		{"(exit 3)", "()"},
+		{"(exit)", "()"},
+		{`(exit "3")`, `error: exit code expected: "3"`},
+		{"(exit 1 2)", "error: arity 1; given 2"},
+	})
+	if len(codes) != 2 || codes[0] != 3 || codes[1] != 0 {
+		t.Errorf("exit codes %v, want [3 0]", codes)
+	}
+}
+
+func TestGetenv(t *testing.T) {
+	t.Setenv("TINYLISP_TEST_VAR", "abc")
+	t.Setenv("TINYLISP_TEST_EMPTY", "")
+	checkEval(t, []evalCase{
+		{`(getenv "TINYLISP_TEST_VAR")`, `"abc"`},
+		{`(getenv "TINYLISP_TEST_EMPTY")`, `""`},
+		{`(getenv "TINYLISP_TEST_NONE")`, "()"},
+		{"(getenv 'TINYLISP_TEST_VAR)", "error: string expected: TINYLISP_TEST_VAR"},
+	})
+}
+
+func TestShebang(t *testing.T) {
+	for _, c := range []struct {
+		src  string
+		want string
+	}{
+		{"#!/usr/bin/env tiny-lisp\n(setq sb-x 1)\n", "1"},
+		{"#!/usr/bin/env tiny-lisp -q\n(setq sb-x (list 2\n3))", "(2 3)"},
+		{"#!/usr/bin/env tiny-lisp", "(2 3)"}, // 何も評価しない。
+	} {
+		if !ReadAndEvalScript("", strings.NewReader(c.src)) {
+			t.Errorf("%q: incomplete", c.src)
+			continue
+		}
+		if got := evalText("sb-x"); got != c.want {
+			t.Errorf("%q: sb-x => %s, want %s", c.src, got, c.want)
+		}
+	}
+}
+
+/*
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,245 @@
+// H25.5/12 (鈴)
+
+// このファイルは関数のトレースを実装する。
+// (trace name...) は関数 name の束縛をトレースする関数に置き換え，
+// (untrace name...) は元に戻す。トレースする関数は呼び出しを引数と
+// ともに，戻りを結果とともに，呼び出しの深さだけ字下げして変数
+// *trace-output* のストリーム (nil ならば *standard-output*) に書く。
+// 深さは呼び出しの環境に記録して future ごとに数え，future での呼び出しは
+// future の通し番号を [f3] のように付けて書く。
+// トレースする関数は戻り値を書くため末尾呼び出しを最適化しない。
+// 関数を defun で定義し直すとトレースは解除される。
+
+package lisp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// トレースの出力先の変数
+var TraceOutputSymbol = NewSymbol("*trace-output*")
+
+// トレースしている関数の元の値. 参照するときは traceLock で排他すること。
+var traced = make(map[*Symbol]Any)
+var traceLock sync.Mutex
+
+// トレースしている間の評価の遅い経路を終える関数
+var endTrace func()
+
+// 組込みのスペシャル・フォーム (トレースできない)
+var specialForms = make(map[*Symbol]bool)
+
+func init() {
+	for sym, val := range builtins {
+		if _, ok := val.(func(*Cell, *Env) (Any, *Env)); ok {
+			specialForms[sym] = true
+		}
+	}
+}
+
+// トレースの深さを環境に記録するための (intern されない) シンボル.
+// トレースする関数は元の関数を呼び出す環境に一つ深い traceLevel を
+// 束縛し，関数の呼び出しの環境はそれを呼び出し元から引き継ぐ。
+var traceSymbol = &Symbol{string: "#<trace level>"}
+
+// トレースする関数の呼び出しの深さと，呼び出した future の通し番号
+// (最初のゴルーチンならば 0)
+type traceLevel struct {
+	depth  int
+	future int64
+}
+
+// future の通し番号の最大値
+var futureCount int64
+
+// 新しい future を評価する環境に束縛するトレースの深さを返す。
+func newFutureTraceLevel() traceLevel {
+	return traceLevel{future: atomic.AddInt64(&futureCount, 1)}
+}
+
+// 環境でのトレースの深さを返す。
+func (env *Env) traceLevel() traceLevel {
+	if v, ok := env.Lookup(traceSymbol); ok {
+		return v.(traceLevel)
+	}
+	return traceLevel{}
+}
+
+// 環境でのトレースの出力先のストリームを返す。
+func traceOutput(env *Env) *Stream {
+	if v, ok := Globals.Lookup(TraceOutputSymbol); ok && v != (*Cell)(nil) {
+		return streamVariable(TraceOutputSymbol)
+	}
+	return standardOutput(env)
+}
+
+// 深さ level のトレースの一行を書く。
+func traceLine(env *Env, level traceLevel, s string) {
+	tag := ""
+	if level.future != 0 {
+		tag = fmt.Sprintf("[f%d] ", level.future)
+	}
+	fmt.Fprintf(traceOutput(env), "%s%s%d: %s\n", tag,
+		strings.Repeat("  ", level.depth), level.depth, s)
+}
+
+// 関数 fn を呼び出しと戻りを書く関数で包む。
+// 包んだ関数は呼び出し元の環境に一つ深いトレースの深さを束縛して
+// fn を呼び出す。
+func traceFunction(name *Symbol, fn Any) func([]Any, *Env) Any {
+	return func(args []Any, env *Env) Any {
+		level := env.traceLevel()
+		traceLine(env, level, StringFor(Cons(name, listFunc(args).(*Cell))))
+		returned := false
+		defer func() {
+			if !returned {
+				traceLine(env, level, StringFor(name)+" exited abnormally")
+			}
+		}()
+		inner := traceLevel{level.depth + 1, level.future}
+		callEnv := &Env{map[*Symbol]Any{traceSymbol: inner}, env, sync.Mutex{}}
+		var val Any
+		switch f := fn.(type) {
+		case func([]Any) Any:
+			val = f(args)
+		case func([]Any, *Env) Any:
+			val = f(args, callEnv)
+		case func(*Cell, *Env) (Any, *Env):
+			a, e := f(quoteList(listFunc(args).(*Cell)), callEnv)
+			if e != nil {
+				a = e.Eval(a)
+			}
+			val = a
+		}
+		returned = true
+		traceLine(env, level, StringFor(name)+" returned "+StringFor(val))
+		return val
+	}
+}
+
+// (trace name...)
+// 関数をトレースする。引数が無ければトレースしている関数のリストを返す。
+func traceForm(x *Cell, env *Env) (Any, *Env) {
+	if x == nil {
+		return tracedList(), nil
+	}
+	var result []Any
+	for ; x != nil; x = x.Cdr {
+		sym, ok := x.Car.(*Symbol)
+		if !ok {
+			panic(fmt.Errorf("symbol expected: %s", StringFor(x.Car)))
+		}
+		traceLock.Lock()
+		_, already := traced[sym]
+		traceLock.Unlock()
+		if !already {
+			fn := env.Get(sym)
+			if !IsFunction(fn) {
+				panic(fmt.Errorf("not function: %s", StringFor(sym)))
+			}
+			if specialForms[sym] {
+				panic(fmt.Errorf("cannot trace special form: %s",
+					StringFor(sym)))
+			}
+			addTraced(sym, fn)
+			env.Set(sym, traceFunction(sym, fn))
+		}
+		result = append(result, sym)
+	}
+	return listFunc(result), nil
+}
+
+// (untrace name...)
+// 関数のトレースをやめる。引数が無ければすべての関数のトレースをやめる。
+func untraceForm(x *Cell, env *Env) (Any, *Env) {
+	if x == nil {
+		x = tracedList()
+	}
+	var result []Any
+	for ; x != nil; x = x.Cdr {
+		sym, ok := x.Car.(*Symbol)
+		if !ok {
+			panic(fmt.Errorf("symbol expected: %s", StringFor(x.Car)))
+		}
+		if fn, ok := removeTraced(sym); ok {
+			env.Set(sym, fn)
+			result = append(result, sym)
+		}
+	}
+	return listFunc(result), nil
+}
+
+// トレースしている関数の名前のリストを名前の順に返す。
+func tracedList() *Cell {
+	traceLock.Lock()
+	var names []*Symbol
+	for sym := range traced {
+		names = append(names, sym)
+	}
+	traceLock.Unlock()
+	sort.Slice(names, func(i, j int) bool {
+		return stringForSymbol(names[i]) < stringForSymbol(names[j])
+	})
+	var list *Cell
+	for i := len(names) - 1; i >= 0; i-- {
+		list = Cons(names[i], list)
+	}
+	return list
+}
+
+// 関数 sym を元の値 fn とともにトレースしていると記録する。
+// トレースしている間は評価の遅い経路をとる。
+func addTraced(sym *Symbol, fn Any) {
+	traceLock.Lock()
+	defer traceLock.Unlock()
+	if len(traced) == 0 {
+		endTrace = beginSlowPath()
+	}
+	traced[sym] = fn
+}
+
+// 関数 sym のトレースの記録を取り除いて元の値を返す。
+// 記録が無ければ論理値に偽を返す。
+func removeTraced(sym *Symbol) (Any, bool) {
+	traceLock.Lock()
+	defer traceLock.Unlock()
+	fn, ok := traced[sym]
+	if ok {
+		delete(traced, sym)
+		if len(traced) == 0 {
+			endTrace()
+		}
+	}
+	return fn, ok
+}
+
+// 関数 name を定義し直したときトレースを解除する。
+func forgetTrace(name *Symbol) {
+	removeTraced(name)
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
@@ -0,0 +1,94 @@
+// H25.5/13 (鈴)
+
+package lisp
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	defer evalText("(untrace)")
+	checkEval(t, []evalCase{
+		{"(defun tr-fact (n) (if (= n 0) 1 (* n (tr-fact (- n 1)))))",
+			"tr-fact"},
+		{"(trace tr-fact)", "(tr-fact)"},
+		{"(trace)", "(tr-fact)"},
+		{"(defun tr-b () 1) (defun tr-a () 2) (trace tr-b car tr-a) (trace)",
+			"(car tr-a tr-b tr-fact)"},
+		{"(untrace tr-a tr-b car)", "(tr-a tr-b car)"},
+		{"(with-output-to-string () (tr-fact 2))",
+			`"0: (tr-fact 2)\n  1: (tr-fact 1)\n    2: (tr-fact 0)\n` +
+				`    2: tr-fact returned 1\n  1: tr-fact returned 1\n` +
+				`0: tr-fact returned 2\n"`},
+		{"(untrace tr-fact)", "(tr-fact)"},
+		{"(list (with-output-to-string () (tr-fact 2)) (trace))", `("" ())`},
+		{"(trace tr-fact) (defun tr-fact (n) n) (trace)", "()"},
+		{"(trace tr-nope)", "error: unbound symbol: tr-nope"},
+		{"(trace if)", "error: cannot trace special form: if"},
+		{"(trace 1)", "error: symbol expected: 1"},
+	})
+}
+
+func TestTraceOutput(t *testing.T) {
+	s := NewStringOutputStream()
+	Globals.Set(TraceOutputSymbol, s)
+	defer Globals.Set(TraceOutputSymbol, (*Cell)(nil))
+	defer evalText("(untrace)")
+	checkEval(t, []evalCase{
+		{"(defun tr-bad (x) (car x)) (trace tr-bad car)", "(tr-bad car)"},
+		{"(with-output-to-string () (tr-bad '(1)))", `""`},
+	})
+	evalText("(tr-bad 1)")
+	want := "0: (tr-bad (1))\n  1: (car (1))\n  1: car returned 1\n" +
+		"0: tr-bad returned 1\n" +
+		"0: (tr-bad 1)\n  1: (car 1)\n  1: car exited abnormally\n" +
+		"0: tr-bad exited abnormally\n"
+	if got := s.Contents(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTraceFuture(t *testing.T) {
+	s := NewStringOutputStream()
+	Globals.Set(TraceOutputSymbol, s)
+	defer Globals.Set(TraceOutputSymbol, (*Cell)(nil))
+	defer evalText("(untrace)")
+	checkEval(t, []evalCase{
+		{"(defun tr-id (x) x) (defun tr-two (x) (tr-id x)) (trace tr-two tr-id)",
+			"(tr-two tr-id)"},
+	})
+	n := atomic.LoadInt64(&futureCount) + 1
+	checkEval(t, []evalCase{
+		{"(tr-id (force (future (tr-two 1))))", "1"},
+	})
+	want := fmt.Sprintf("[f%d] 0: (tr-two 1)\n[f%d]   1: (tr-id 1)\n"+
+		"[f%d]   1: tr-id returned 1\n[f%d] 0: tr-two returned 1\n",
+		n, n, n, n) + "0: (tr-id 1)\n0: tr-id returned 1\n"
+	if got := s.Contents(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+/*
+  Copyright (c) 2013 OKI Software Co., Ltd.
+
+  Permission is hereby granted, free of charge, to any person obtaining a
+  copy of this software and associated documentation files (the "Software"),
+  to deal in the Software without restriction, including without limitation
+  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+  and/or sell copies of the Software, and to permit persons to whom the
+  Software is furnished to do so, subject to the following conditions:
+
+  The above copyright notice and this permission notice shall be included in
+  all copies or substantial portions of the Software.
+
+  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL
+  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+  FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER
+  DEALINGS IN THE SOFTWARE.
+*/
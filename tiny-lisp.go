@@ -1,25 +1,33 @@
-// H25.3/18 - 3/28 (鈴)
+// H25.3/18 - 5/12 (鈴)
 package main
 
 import (
-	"bufio"
-	"fmt"
+	"github.com/pkelchte/tiny-lisp/lineedit"
 	"github.com/pkelchte/tiny-lisp/lisp"
+	"flag"
+	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
 	"strings"
 )
 
-// 初期化スクリプト
+// 初期化スクリプト. 関数はパッケージ lisp に定義する。
 var prelude = `
-(defun null (x) (eq x nil))
-(defun not (x) (eq x nil))
+(in-package lisp)
+
+(defun null (x) "(null x) Return t if x is nil." (eq x nil))
+(defun not (x) "(not x) Return t if x is false." (eq x nil))
 
 (defun length (x)
+  "(length list) Return the number of elements of list."
   (if (null x)
       0
     (+ 1 (length (cdr x)))))
 
 (defun append (&rest x)
+  "(append list...) Return the concatenation of the lists."
   (if (null x)
       nil
     (if (null (cdr x))
@@ -30,55 +38,262 @@ var prelude = `
   (if (null x)
       y
     (cons (car x) (_append (cdr x) y))))
+
+(in-package user)
 `
 
+// パニックから回復してエラーを表示する。defer で呼ぶこと。
+func printError() {
+	if r := recover(); r != nil {
+		switch e := r.(type) {
+		case error:
+			fmt.Printf("==> %s\n", e.Error())
+		case string:
+			fmt.Printf("===> %s\n", e)
+		default:
+			panic(e)
+		}
+	}
+}
+
 // 文字列を読み込み式を評価して結果を表示する。
 // 式が不完全ならば done 引数に false を与えて終わる。
 func readEvalPrint(line string, done *bool) {
+	defer printError()
+	*done = true
+	if !lisp.ReadEvalPrint(strings.NewReader(line), os.Stdout) {
+		*done = false
+	}
+}
+
+// コマンド行で与えた順に実行する -e と -l の動作
+type actionFlag struct {
+	actions *[]func()
+	do      func(string)
+}
+
+func (f actionFlag) String() string { return "" }
+
+func (f actionFlag) Set(s string) error {
+	*f.actions = append(*f.actions, func() { f.do(s) })
+	return nil
+}
+
+// 文字列の式を評価する。式が不完全ならばパニックする。
+func evalString(s string) {
+	if !lisp.ReadAndEval(s) {
+		panic(fmt.Errorf("incomplete expression: %s", s))
+	}
+}
+
+// スクリプトのファイル (- ならば標準入力) を評価する。
+// 式が不完全ならばパニックする。
+func evalFile(fileName string) {
+	if fileName == "-" {
+		lisp.ReadAndEvalStdin()
+	} else if !lisp.ReadAndEvalFile(fileName) {
+		panic(fmt.Errorf("incomplete expression: %s", fileName))
+	}
+}
+
+// 関数を実行する。パニックすればエラーを標準エラー出力に表示して
+// false を返す。
+func protect(f func()) (ok bool) {
 	defer func() {
 		if r := recover(); r != nil {
-			switch e := r.(type) {
-			case error:
-				fmt.Printf("==> %s\n", e.Error())
-			case string:
-				fmt.Printf("===> %s\n", e)
-			default:
-				panic(e)
-			}
+			fmt.Fprintf(os.Stderr, "tiny-lisp: %v\n", r)
+			ok = false
 		}
 	}()
-	*done = true
-	if !lisp.ReadEvalPrint(strings.NewReader(line), os.Stdout) {
-		*done = false
+	f()
+	return true
+}
+
+// フラグがコマンド行で与えられていれば true を返す。
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// Lisp の関数のプロファイルを終えてファイルと標準エラー出力に書く。
+func writeLispProfile(f *os.File) {
+	p := lisp.StopProfiling()
+	if p == nil {
+		return
 	}
+	if err := p.WritePprof(f); err != nil {
+		fmt.Fprintf(os.Stderr, "tiny-lisp: %v\n", err)
+	}
+	f.Close()
+	p.WriteReport(os.Stderr)
 }
 
+const usage = `usage: tiny-lisp [flags] [script [arg...]] [-]
+
+Run the script with the args in *argv*, or start an interactive session
+if neither a script nor -e is given. The script - reads standard input,
+or starts an interactive session on a terminal. A trailing - starts an
+interactive session after the script.
+
+`
+
 // Lisp スクリプトまたは Lisp 対話セッションを実行する。
+// エラーで終われば終了コード 1 を返す (フラグの誤りは flag パッケージが
+// 終了コード 2 で終える)。
 func main() {
-	lisp.ReadAndEval(prelude)
-	n := len(os.Args)
-	if n >= 2 && os.Args[1] != "-" {
-		lisp.ReadAndEvalFile(os.Args[1])
-	}
-	if n < 2 || os.Args[n-1] == "-" {
-		// 対話セッションを始める。
-		var rd *bufio.Reader = bufio.NewReader(os.Stdin)
+	os.Exit(run())
+}
+
+func run() int {
+	var actions []func()
+	flag.Var(actionFlag{&actions, evalString}, "e",
+		"evaluate `expressions` (repeatable)")
+	flag.Var(actionFlag{&actions, lisp.Load}, "l", "load `file` (repeatable)")
+	noPrelude := flag.Bool("no-prelude", false, "do not define the prelude functions")
+	profile := flag.String("profile", "", "write a CPU profile to `file`")
+	lispProfile := flag.String("lisp-profile", "",
+		"profile the Lisp functions, write them in pprof format to `file`\n"+
+			"and show a summary on standard error")
+	maxDepth := flag.Int64("max-depth", 0,
+		"limit the nesting of evaluation to `n` levels (0 means no limit)")
+	procs := flag.Int("gomaxprocs", 0, "run on at most `n` CPUs (0 means all)")
+	debug := flag.Bool("debug", false, "enter a break loop on errors in functions\n"+
+		"(default true in an interactive session on a terminal)")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *procs > 0 {
+		runtime.GOMAXPROCS(*procs)
+	}
+	lisp.SetMaxDepth(*maxDepth)
+	finish := func() {}
+	if *profile != "" {
+		pf, err := os.Create(*profile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tiny-lisp: %v\n", err)
+			return 1
+		}
+		pprof.StartCPUProfile(pf)
+		finish = func() {
+			pprof.StopCPUProfile()
+			pf.Close()
+		}
+	}
+	if *lispProfile != "" {
+		lf, err := os.Create(*lispProfile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tiny-lisp: %v\n", err)
+			return 1
+		}
+		lisp.StartProfiling()
+		goFinish := finish
+		finish = func() {
+			goFinish()
+			writeLispProfile(lf)
+		}
+	}
+	defer finish()
+	lisp.ExitHook = func(code int) {
+		finish()
+		os.Exit(code)
+	}
+	args := flag.Args()
+	script := ""
+	if len(args) > 0 {
+		script, args = args[0], args[1:]
+	}
+	interactive := len(actions) == 0 && script == ""
+	if n := len(args); n > 0 && args[n-1] == "-" {
+		interactive = true
+		args = args[:n-1]
+	}
+	terminal := lineedit.IsTerminal(int(os.Stdin.Fd()))
+	if script == "-" && terminal {
+		script, interactive = "", true
+	}
+	if !isFlagSet("debug") {
+		*debug = interactive && terminal
+	}
+	var ed *lineedit.Editor
+	if *debug || interactive {
+		ed = newEditor()
+	}
+	if *debug {
+		lisp.EnableDebugger(ed.ReadLine, os.Stdout)
+	}
+	if !*noPrelude {
+		lisp.ReadAndEval(prelude)
+	}
+	lisp.SetArgs(args)
+	for _, action := range actions {
+		if !protect(action) {
+			return 1
+		}
+	}
+	if script != "" && !protect(func() { evalFile(script) }) {
+		return 1
+	}
+	if interactive {
+		lisp.SaveGlobals()
+		interact(ed)
+	}
+	return 0
+}
+
+// 履歴のファイル名 (ホームディレクトリの下)
+const historyFileName = ".tiny-lisp_history"
+
+// メタコマンドの行を実行する。セッションを終えるべきならば true を返す。
+func metaCommand(line string) (quit bool) {
+	defer printError()
+	return lisp.MetaCommand(line, os.Stdout)
+}
+
+// 標準入力から読む行エディタを作る。入力のバッファは *standard-input*
+// と共有するから，read-line などは行エディタが読んだ後の入力を読む。
+func newEditor() *lineedit.Editor {
+	ed := lineedit.NewWithReader(os.Stdin, lisp.StdinReader(), os.Stdout)
+	ed.NeedsMore = lineedit.Incomplete
+	ed.Complete = lisp.Completions
+	if home := os.Getenv("HOME"); home != "" {
+		ed.History.Load(filepath.Join(home, historyFileName))
+	}
+	return ed
+}
+
+// 対話セッションを行う。端末ならば行エディタで入力を編集できる。
+// : で始まるメタコマンドも受け付ける (:help で一覧を表示する)。
+func interact(ed *lineedit.Editor) {
+	for {
+		prompt := "> "
+		line := ""
 		for {
-			fmt.Print("> ")
-			line := ""
-			for {
-				s, err := rd.ReadString('\n')
-				if err != nil {
+			s, err := ed.ReadLine(prompt)
+			if err == lineedit.ErrInterrupted {
+				break
+			} else if err != nil {
+				return
+			}
+			if line == "" && lisp.IsMetaCommand(s) {
+				if metaCommand(s) {
 					return
 				}
-				line += s
-				var done bool
-				readEvalPrint(line, &done)
-				if done {
-					break
-				}
-				fmt.Print("  ")
+				break
+			}
+			line += s + "\n"
+			var done bool
+			readEvalPrint(line, &done)
+			if done {
+				break
 			}
+			prompt = "  "
 		}
 	}
 }